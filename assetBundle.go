@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticIntf"
+	"github.com/ingmardrewing/staticPersistence"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultJsFileName = "logic.js"
+
+	// number of hex digits of the content
+	// hash within the bundle filenames
+	bundleHashLength = 10
+)
+
+var (
+	cssCommentRegex = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssSpaceRegex   = regexp.MustCompile(`\s*([{};,>])\s*`)
+	assetRefRegex   = regexp.MustCompile(`(?i)(\s(?:href|src)\s*=\s*["'])([^"']*)(["'])`)
+)
+
+// Returns the configured name of the js bundle,
+// or defaultJsFileName if there is none
+func bundleJsFileName(config staticPersistence.Config) string {
+	if config.Deploy.JsFileName == "" {
+		return defaultJsFileName
+	}
+	return config.Deploy.JsFileName
+}
+
+// Creates an assetBundler writing the bundles into the
+// target dir, named after the given css and js filenames
+func NewAssetBundler(
+	domain, targetDir, cssFileName, jsFileName string,
+	settings bundleSettings) *assetBundler {
+
+	b := new(assetBundler)
+	b.domain = domain
+	b.targetDir = targetDir
+	b.cssFileName = cssFileName
+	b.jsFileName = jsFileName
+	b.settings = settings
+	b.bundles = map[string]string{}
+	return b
+}
+
+// The assetBundler joins the css and the js of the
+// components into one file each, named with a hash of
+// their content, so browsers never use an outdated copy,
+// and points the html files to them. The css is minified,
+// the js is kept as it is, as minifying it safely takes
+// a parser of its strings, template literals and regexps.
+type assetBundler struct {
+	domain      string
+	targetDir   string
+	cssFileName string
+	jsFileName  string
+	settings    bundleSettings
+
+	// the filenames of the bundles and of the
+	// fingerprinted files, by the filenames they
+	// replace, as paths from the doc root
+	bundles map[string]string
+}
+
+// The css or js of one component
+type bundleSource struct {
+	name    string
+	content string
+}
+
+// Creates the file containers of the css bundle, the js bundle
+// if there is any js, and of their source maps if configured
+func (b *assetBundler) Bundle(components []staticIntf.Component) []fs.FileContainer {
+	css := []bundleSource{}
+	js := []bundleSource{}
+	for _, cmp := range components {
+		name := strings.TrimPrefix(identity(cmp), "*")
+		css = append(css, bundleSource{name + ".css", cmp.GetCss()})
+		if cmp.GetJs() != "" {
+			js = append(js, bundleSource{name + ".js", cmp.GetJs()})
+		}
+	}
+
+	fcs := b.bundle(b.cssFileName, css, minifyCssLine, "/*# sourceMappingURL=%s */")
+	if len(js) > 0 {
+		fcs = append(fcs, b.bundle(b.jsFileName, js, nil, "//# sourceMappingURL=%s")...)
+	}
+	return fcs
+}
+
+// Tells if one of the components has js to bundle
+func hasJs(components []staticIntf.Component) bool {
+	for _, cmp := range components {
+		if cmp.GetJs() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Minifies and joins the sources into one file, named
+// after the given filename with the content hash added.
+// Without minifyLine the lines are joined unchanged.
+func (b *assetBundler) bundle(
+	filename string,
+	sources []bundleSource,
+	minifyLine func(string) string,
+	mapComment string) []fs.FileContainer {
+
+	lines := []string{}
+	sm := newSourceMap()
+	for i, src := range sources {
+		content := src.content
+		if filepath.Ext(filename) == ".css" {
+			content = blankCssComments(content)
+		}
+		for l, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			if minifyLine == nil {
+				lines = append(lines, line)
+				sm.addLine(i, l, 0)
+				continue
+			}
+			minified := minifyLine(line)
+			if minified == "" {
+				continue
+			}
+			lines = append(lines, minified)
+			sm.addLine(i, l, len(line)-len(strings.TrimLeft(line, " \t")))
+		}
+	}
+	data := strings.Join(lines, "\n")
+	if data != "" {
+		data += "\n"
+	}
+
+	hashed := fingerprint(filename, data)
+	b.bundles[filename] = hashed
+	fcs := []fs.FileContainer{}
+
+	if b.settings.SourceMaps {
+		mapName := hashed + ".map"
+		mapData, err := sm.encode(hashed, sources)
+		if err != nil {
+			log.Errorf("creating the source map of %s: %v", hashed, err)
+		} else {
+			data += fmt.Sprintf(mapComment, mapName) + "\n"
+			fcs = append(fcs, b.fileContainer(mapName, string(mapData)))
+		}
+	}
+	return append([]fs.FileContainer{b.fileContainer(hashed, data)}, fcs...)
+}
+
+// Points the references to the given files to their
+// fingerprinted names as well, both given as paths
+// from the doc root without leading slash
+func (b *assetBundler) AddFingerprints(fingerprints map[string]string) {
+	for file, hashed := range fingerprints {
+		b.bundles[file] = hashed
+	}
+}
+
+// Adds the hash of the data to the filename,
+// like styles.css becomes styles.3f2a9c01de.css
+func fingerprint(filename, data string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "." + contentHash(data)[:bundleHashLength] + ext
+}
+
+func (b *assetBundler) fileContainer(filename, data string) fs.FileContainer {
+	fc := fs.NewFileContainer()
+	fc.SetDataAsString(data)
+	fc.SetPath(b.targetDir)
+	fc.SetFilename(filename)
+	return fc
+}
+
+// Points the references of the given html files to the
+// bundled and the fingerprinted files to their new names.
+// Where the bundles are included is up to the contexts
+// and templates rendering the html.
+func (b *assetBundler) Rewrite(fcs []fs.FileContainer) {
+	for _, fc := range fcs {
+		if filepath.Ext(fc.GetFilename()) != ".html" {
+			continue
+		}
+		html := fc.GetDataAsString()
+		rewritten := assetRefRegex.ReplaceAllStringFunc(html, func(attr string) string {
+			m := assetRefRegex.FindStringSubmatch(attr)
+			if hashed, ok := b.bundleOf(m[2]); ok {
+				return m[1] + hashed + m[3]
+			}
+			return attr
+		})
+		if rewritten != html {
+			fc.SetDataAsString(rewritten)
+		}
+	}
+}
+
+// Returns the reference of the bundle replacing the file
+// the given reference points to, if it is one of the
+// bundled files at the doc root of the site
+func (b *assetBundler) bundleOf(ref string) (string, bool) {
+	u, err := url.Parse(ref)
+	if err != nil || (u.Host != "" && u.Host != b.domain) {
+		return "", false
+	}
+	hashed, ok := b.bundles[strings.TrimPrefix(u.Path, "/")]
+	if !ok || !strings.HasPrefix(u.Path, "/") {
+		return "", false
+	}
+	u.Path = "/" + hashed
+	u.RawQuery = ""
+	return u.String(), true
+}
+
+// Replaces the comments of the css by the line breaks
+// within them, to keep the line numbers of the rules
+func blankCssComments(css string) string {
+	return cssCommentRegex.ReplaceAllStringFunc(css, func(comment string) string {
+		return strings.Repeat("\n", strings.Count(comment, "\n"))
+	})
+}
+
+// Minifies a line of css, line breaks are kept
+// so the source maps can point to the rules
+func minifyCssLine(line string) string {
+	line = strings.Join(strings.Fields(line), " ")
+	line = cssSpaceRegex.ReplaceAllString(line, "$1")
+	return strings.Replace(line, ";}", "}", -1)
+}
+
+// A source map version 3, mapping each line
+// of a bundle to the line of its source
+type sourceMap struct {
+	mappings []string
+	prev     [3]int
+}
+
+func newSourceMap() *sourceMap {
+	return new(sourceMap)
+}
+
+// Maps the next line of the bundle to the given
+// line and column of the source with the given index
+func (s *sourceMap) addLine(source, line, column int) {
+	segment := vlq(0) + vlq(source-s.prev[0]) + vlq(line-s.prev[1]) + vlq(column-s.prev[2])
+	s.prev = [3]int{source, line, column}
+	s.mappings = append(s.mappings, segment)
+}
+
+func (s *sourceMap) encode(file string, sources []bundleSource) ([]byte, error) {
+	names := []string{}
+	contents := []string{}
+	for _, src := range sources {
+		names = append(names, src.name)
+		contents = append(contents, src.content)
+	}
+	return json.Marshal(map[string]interface{}{
+		"version":        3,
+		"file":           file,
+		"sources":        names,
+		"sourcesContent": contents,
+		"names":          []string{},
+		"mappings":       strings.Join(s.mappings, ";")})
+}
+
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// Encodes the number as base64 VLQ, as used by source maps
+func vlq(n int) string {
+	v := n << 1
+	if n < 0 {
+		v = (-n << 1) | 1
+	}
+	encoded := ""
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		encoded += string(base64Digits[digit])
+		if v == 0 {
+			return encoded
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticIntf"
+)
+
+type testComponent struct {
+	css string
+	js  string
+}
+
+func (c *testComponent) GetCss() string { return c.css }
+
+func (c *testComponent) GetJs() string { return c.js }
+
+func givenBundler(sourceMaps bool) (*assetBundler, map[string]string) {
+	b := NewAssetBundler("drewing.de", "deploy", "styles.css", "logic.js", bundleSettings{SourceMaps: sourceMaps})
+	fcs := b.Bundle([]staticIntf.Component{
+		&testComponent{css: "/* header */\n.header {\n\tcolor : red;\n}\n", js: "// greet\nvar a = 1;\n\tconsole.log(a);\n"},
+		&testComponent{css: ".footer > a { margin: 0 ; }"}})
+	files := map[string]string{}
+	for _, fc := range fcs {
+		files[fc.GetFilename()] = fc.GetDataAsString()
+	}
+	return b, files
+}
+
+func TestBundlesAreMinifiedAndHashed(t *testing.T) {
+	b, files := givenBundler(false)
+
+	css := b.bundles["styles.css"]
+	if !strings.HasPrefix(css, "styles.") || len(css) != len("styles..css")+bundleHashLength {
+		t.Error("Expected a hashed css filename, but got", css)
+	}
+	expected := ".header{\ncolor : red;\n}\n.footer>a{margin: 0}\n"
+	if files[css] != expected {
+		t.Error("Expected", expected, ", but got", files[css])
+	}
+
+	js := b.bundles["logic.js"]
+	expected = "// greet\nvar a = 1;\n\tconsole.log(a);\n"
+	if files[js] != expected {
+		t.Error("Expected", expected, ", but got", files[js])
+	}
+	if len(files) != 2 {
+		t.Error("Expected", 2, "files without source maps, but got", len(files))
+	}
+}
+
+func TestJsBundleKeepsTemplateLiterals(t *testing.T) {
+	js := "const card = `\n  <a href=\"${url}\">\n//cdn.drewing.de/${img}\n\n  </a>`;\n"
+	b := NewAssetBundler("drewing.de", "deploy", "styles.css", "logic.js", bundleSettings{})
+	fcs := b.Bundle([]staticIntf.Component{&testComponent{js: js}})
+
+	for _, fc := range fcs {
+		if fc.GetFilename() == b.bundles["logic.js"] && fc.GetDataAsString() != js {
+			t.Error("Expected", js, ", but got", fc.GetDataAsString())
+		}
+	}
+}
+
+func TestBundleHashChangesWithContent(t *testing.T) {
+	b1 := NewAssetBundler("drewing.de", "deploy", "styles.css", "logic.js", bundleSettings{})
+	b1.Bundle([]staticIntf.Component{&testComponent{css: "a{}"}})
+	b2 := NewAssetBundler("drewing.de", "deploy", "styles.css", "logic.js", bundleSettings{})
+	b2.Bundle([]staticIntf.Component{&testComponent{css: "b{}"}})
+
+	if b1.bundles["styles.css"] == b2.bundles["styles.css"] {
+		t.Error("Expected different filenames, but got", b1.bundles["styles.css"], "twice")
+	}
+	if _, ok := b1.bundles["logic.js"]; ok {
+		t.Error("Expected no js bundle without js")
+	}
+}
+
+func TestBundleSourceMaps(t *testing.T) {
+	b, files := givenBundler(true)
+
+	js := b.bundles["logic.js"]
+	if !strings.HasSuffix(files[js], "//# sourceMappingURL="+js+".map\n") {
+		t.Error("Expected a reference to the source map, but got", files[js])
+	}
+	sm := struct {
+		Version        int      `json:"version"`
+		File           string   `json:"file"`
+		Sources        []string `json:"sources"`
+		SourcesContent []string `json:"sourcesContent"`
+		Mappings       string   `json:"mappings"`
+	}{}
+	if err := json.Unmarshal([]byte(files[js+".map"]), &sm); err != nil {
+		t.Fatal(err)
+	}
+	if sm.Version != 3 || sm.File != js || sm.Sources[0] != "main.testComponent.js" {
+		t.Error("Expected a version 3 map of", js, ", but got", sm)
+	}
+
+	// each line maps to the same line of the source
+	if sm.Mappings != "AAAA;AACA;AACA" {
+		t.Error("Expected", "AAAA;AACA;AACA", ", but got", sm.Mappings)
+	}
+
+	css := b.bundles["styles.css"]
+	if !strings.Contains(files[css+".map"], `"mappings":"AACA;AACC;AACD;ACHA"`) {
+		t.Error("Expected the mappings of both css sources, but got", files[css+".map"])
+	}
+}
+
+func TestBundlerRewritesReferences(t *testing.T) {
+	b, _ := givenBundler(false)
+	page := fs.NewFileContainer()
+	page.SetFilename("index.html")
+	page.SetDataAsString(`<html><head>` +
+		`<link rel="stylesheet" href="/styles.css?v=1">` +
+		`<link rel="stylesheet" href="https://drewing.de/styles.css">` +
+		`<link rel="stylesheet" href="https://example.com/styles.css">` +
+		`<link rel="stylesheet" href="/blog/styles.css">` +
+		`</head><body></body></html>`)
+	index := fs.NewFileContainer()
+	index.SetFilename("search.json")
+	index.SetDataAsString(`{"href": "/styles.css"}`)
+
+	b.Rewrite([]fs.FileContainer{page, index})
+
+	css := b.bundles["styles.css"]
+	expected := `<html><head>` +
+		`<link rel="stylesheet" href="/` + css + `">` +
+		`<link rel="stylesheet" href="https://drewing.de/` + css + `">` +
+		`<link rel="stylesheet" href="https://example.com/styles.css">` +
+		`<link rel="stylesheet" href="/blog/styles.css">` +
+		`</head><body></body></html>`
+	if page.GetDataAsString() != expected {
+		t.Error("Expected", expected, ", but got", page.GetDataAsString())
+	}
+	if index.GetDataAsString() != `{"href": "/styles.css"}` {
+		t.Error("Expected only html to be rewritten, but got", index.GetDataAsString())
+	}
+}
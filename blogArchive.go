@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ingmardrewing/staticIntf"
+	"github.com/ingmardrewing/staticModel"
+)
+
+var slugRegex = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// Turns a tag or category into a path segment
+func slugify(name string) string {
+	return strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// Creates a blogArchive for the blog located
+// below the given subDir of the site
+func NewBlogArchive(site staticIntf.Site, subDir string) *blogArchive {
+	a := new(blogArchive)
+	a.site = site
+	a.subDir = subDir
+	a.categories = newArchiveIndex("categories", labelTitle("Category"))
+	a.tags = newArchiveIndex("tags", labelTitle("Tag"))
+	a.tags.overviewPath = "tags"
+	a.tags.overviewTitle = "Tags"
+	a.years = newArchiveIndex("", countTitle)
+	a.years.overviewPath = "archive"
+	a.years.overviewTitle = "Archive"
+	a.months = newArchiveIndex("", countTitle)
+	a.links = map[string]archiveLinks{}
+	return a
+}
+
+// The blogArchive creates an archive of the posts of
+// each category, tag, year and month, paginated like
+// the navi pages of the blog, and overviews of all
+// tags and all years
+type blogArchive struct {
+	site       staticIntf.Site
+	subDir     string
+	settings   srcSettings
+	categories *archiveIndex
+	tags       *archiveIndex
+	years      *archiveIndex
+	months     *archiveIndex
+	links      map[string]archiveLinks
+}
+
+// Posts of a blog grouped by one of their
+// fields, e.g. by tag
+type archiveIndex struct {
+	dir           string
+	title         func(name string, count int) string
+	overviewPath  string
+	overviewTitle string
+	names         map[string]string
+	locations     map[string][]string
+}
+
+func newArchiveIndex(dir string, title func(string, int) string) *archiveIndex {
+	i := new(archiveIndex)
+	i.dir = dir
+	i.title = title
+	i.names = map[string]string{}
+	i.locations = map[string][]string{}
+	return i
+}
+
+// Titles archives like "Tag: go"
+func labelTitle(label string) func(string, int) string {
+	return func(name string, count int) string {
+		return label + ": " + name
+	}
+}
+
+// Titles archives like "June 2009 (3 posts)"
+func countTitle(name string, count int) string {
+	if count == 1 {
+		return name + " (1 post)"
+	}
+	return fmt.Sprintf("%s (%d posts)", name, count)
+}
+
+// Adds the post at the given location to the archive
+// of the given slug and name, returns the slug
+func (i *archiveIndex) add(slug, name, location string) string {
+	if slug == "" {
+		return ""
+	}
+	if _, ok := i.names[slug]; !ok {
+		i.names[slug] = name
+	}
+	i.locations[slug] = append(i.locations[slug], location)
+	return slug
+}
+
+// Tells if the post at the given location
+// is part of the archive of the given slug
+func (i *archiveIndex) contains(slug, location string) bool {
+	for _, l := range i.locations[slug] {
+		if l == location {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *archiveIndex) slugs() []string {
+	slugs := []string{}
+	for slug := range i.names {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+// Returns the path of the archive of the given slug
+func (a *blogArchive) archivePath(i *archiveIndex, slug string) string {
+	return path.Join(a.subDir, i.dir, slug)
+}
+
+func (a *blogArchive) archiveUrl(i *archiveIndex, slug string) string {
+	return "https://" + a.site.Domain() + "/" + a.archivePath(i, slug) + "/"
+}
+
+// Records the category, tags and date of a post and the
+// links to the archives of its category and tags, to be
+// used as pageTransform. The content is left as it is, the
+// links are added when the post is rendered, see Links.
+func (a *blogArchive) collectPost(doc *pageDoc) (bool, error) {
+	if a.site == nil {
+		return false, nil
+	}
+	location := path.Join(doc.PathFromDocRoot, doc.Filename)
+	if date, err := time.Parse("2006-01-02", doc.CreateDate); err == nil {
+		a.years.add(date.Format("2006"), date.Format("2006"), location)
+		a.months.add(date.Format("2006/01"), date.Format("January 2006"), location)
+	}
+
+	links := archiveLinks{}
+	if slug := a.categories.add(slugify(doc.Category), doc.Category, location); slug != "" {
+		links.Category = &templateLink{doc.Category, a.archiveUrl(a.categories, slug)}
+	}
+	for _, tag := range doc.Tags {
+		if slug := a.tags.add(slugify(tag), tag, location); slug != "" {
+			links.Tags = append(links.Tags, templateLink{tag, a.archiveUrl(a.tags, slug)})
+		}
+	}
+	if links.Category != nil || len(links.Tags) > 0 {
+		a.links[location] = links
+	}
+	return false, nil
+}
+
+// Returns the links of the posts to the archives
+// of their category and tags, by their location
+func (a *blogArchive) Links() map[string]archiveLinks {
+	return a.links
+}
+
+// Sources linking their posts to archives
+type archiveSource interface {
+	ArchiveLinks() map[string]archiveLinks
+}
+
+// The links of a post to the archives of its category and tags
+type archiveLinks struct {
+	Category *templateLink
+	Tags     []templateLink
+}
+
+// Returns the url of the first of the links
+func (l archiveLinks) first() string {
+	if l.Category != nil {
+		return l.Category.Url
+	}
+	if len(l.Tags) > 0 {
+		return l.Tags[0].Url
+	}
+	return ""
+}
+
+// Creates one container per archive holding its pages,
+// and one per overview. The posts are taken from the
+// given container of the blog, in its order.
+func (a *blogArchive) Containers(posts staticIntf.PagesContainer) []staticIntf.PagesContainer {
+	if a.site == nil {
+		return nil
+	}
+
+	containers := []staticIntf.PagesContainer{}
+	for _, i := range []*archiveIndex{a.categories, a.tags, a.years, a.months} {
+		overview := []staticIntf.Page{}
+		for _, slug := range i.slugs() {
+			c := a.archiveContainer(i, slug, posts)
+			if len(c.NaviPages()) == 0 {
+				continue
+			}
+			containers = append(containers, c)
+			naviPages := c.NaviPages()
+			overview = append(overview, naviPages[len(naviPages)-1])
+		}
+		if i.overviewPath != "" && len(overview) > 0 {
+			containers = append(containers, a.overviewContainer(i, posts, overview))
+		}
+	}
+	return containers
+}
+
+// Creates the container holding the paginated
+// archive pages of the given slug
+func (a *blogArchive) archiveContainer(
+	i *archiveIndex,
+	slug string,
+	posts staticIntf.PagesContainer) staticIntf.PagesContainer {
+
+	archived := []staticIntf.Page{}
+	for _, p := range posts.Pages() {
+		if i.contains(slug, path.Join(p.PathFromDocRoot(), p.HtmlFilename())) {
+			archived = append(archived, p)
+		}
+	}
+
+	title := i.title(i.names[slug], len(archived))
+	c := staticModel.NewPagesContainer(posts.Variant(), title)
+	if len(archived) == 0 {
+		return c
+	}
+	archive := staticModel.NewPagesContainer(posts.Variant(), title)
+	for _, p := range archived {
+		archive.AddPage(p)
+	}
+	g := NewBlogNaviPageGenerator(a.site, a.archivePath(i, slug), archive)
+	g.title = title
+	g.useSettings(a.settings)
+	for _, p := range g.Createpages() {
+		c.AddNaviPage(p)
+		p.Container(c)
+	}
+	return c
+}
+
+// Creates the container holding the page which
+// links the first page of each archive
+func (a *blogArchive) overviewContainer(
+	i *archiveIndex,
+	posts staticIntf.PagesContainer,
+	archives []staticIntf.Page) staticIntf.PagesContainer {
+
+	pm := staticModel.NewPageMaker()
+	pm.Title(i.overviewTitle)
+	pm.Category("blog post navi")
+	pm.PathFromDocRoot(path.Join(a.subDir, i.overviewPath))
+	pm.FileName("index.html")
+	pm.Site(a.site)
+	pm.NavigatedPages(archives...)
+
+	c := staticModel.NewPagesContainer(posts.Variant(), i.overviewTitle)
+	c.AddNaviPage(pm.Make())
+	return c
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/staticIntf"
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+func givenTaggedBlog(t *testing.T) (*siteCreator, string) {
+	dir, _ := ioutil.TempDir("", "archive")
+	blogDir := filepath.Join(dir, "posts")
+	os.MkdirAll(blogDir, 0755)
+
+	posts := []struct{ tags, category, date string }{
+		{"[go, static]", "code", "2009-06-13"},
+		{"[drawing]", "art", "2009-07-01"},
+		{"[Go]", "code", "2010-01-05"}}
+	for i, p := range posts {
+		ioutil.WriteFile(filepath.Join(blogDir, fmt.Sprintf("doc%05d.md", i)), []byte(fmt.Sprintf(
+			"---\ntitle: Post %d\ntags: %s\ncategory: %s\ncreate_date: %s\npath: /blog/post-%d/\n---\ntext\n",
+			i, p.tags, p.category, p.date, i)), 0644)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(fmt.Sprintf(`[{
+		"domain": "drewing.de",
+		"deploy": {"targetDir": %q},
+		"src": [{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog"}]
+	}]`, filepath.Join(dir, "deploy"), blogDir)), 0644)
+	config := staticPersistence.ReadConfig(dir, "config.json")[0]
+
+	s := NewSiteCreator(config)
+	s.options.jobs = 1
+	s.addSite()
+	s.addSources()
+	s.addContainers()
+	return s, dir
+}
+
+func naviPagesByPath(containers []staticIntf.PagesContainer) map[string]staticIntf.Page {
+	pages := map[string]staticIntf.Page{}
+	for _, c := range containers {
+		for _, p := range c.NaviPages() {
+			pages[p.PathFromDocRoot()+"/"+p.HtmlFilename()] = p
+		}
+	}
+	return pages
+}
+
+func TestBlogArchivePages(t *testing.T) {
+	s, dir := givenTaggedBlog(t)
+	defer os.RemoveAll(dir)
+	if err := s.errs.orNil(); err != nil {
+		t.Fatal(err)
+	}
+
+	pages := naviPagesByPath(s.site.Containers())
+	cases := map[string]int{
+		"blog/tags/go/index.html":         2,
+		"blog/tags/static/index.html":     1,
+		"blog/tags/drawing/index.html":    1,
+		"blog/categories/code/index.html": 2,
+		"blog/categories/art/index.html":  1,
+		"blog/tags/index.html":            3}
+	for location, expected := range cases {
+		p, ok := pages[location]
+		if !ok {
+			t.Error("Expected an archive page at", location)
+			continue
+		}
+		if len(p.NavigatedPages()) != expected {
+			t.Error("Expected", expected, "pages in", location, ", but got", len(p.NavigatedPages()))
+		}
+	}
+	if title := pages["blog/tags/go/index.html"].Title(); title != "Tag: go" {
+		t.Error("Expected Tag: go, but got", title)
+	}
+}
+
+func TestBlogDateArchivePages(t *testing.T) {
+	s, dir := givenTaggedBlog(t)
+	defer os.RemoveAll(dir)
+
+	pages := naviPagesByPath(s.site.Containers())
+	cases := []struct {
+		location, title string
+		count           int
+	}{
+		{"blog/2009/index.html", "2009 (2 posts)", 2},
+		{"blog/2010/index.html", "2010 (1 post)", 1},
+		{"blog/2009/06/index.html", "June 2009 (1 post)", 1},
+		{"blog/2009/07/index.html", "July 2009 (1 post)", 1},
+		{"blog/2010/01/index.html", "January 2010 (1 post)", 1},
+		{"blog/archive/index.html", "Archive", 2}}
+	for _, c := range cases {
+		p, ok := pages[c.location]
+		if !ok {
+			t.Error("Expected an archive page at", c.location)
+			continue
+		}
+		if p.Title() != c.title || len(p.NavigatedPages()) != c.count {
+			t.Error("Expected", c.title, "with", c.count, "pages at", c.location,
+				", but got", p.Title(), len(p.NavigatedPages()))
+		}
+	}
+}
+
+func TestBlogArchiveLinksTagsFromPosts(t *testing.T) {
+	s, dir := givenTaggedBlog(t)
+	defer os.RemoveAll(dir)
+
+	post := s.sources[0].Container().Pages()[0]
+	if strings.Contains(post.Content(), `class="tags"`) {
+		t.Error("Expected the content of the post to be left as it is, but got", post.Content())
+	}
+
+	s.addLocations()
+	s.addContexts()
+	s.fillFileContainers(s.config)
+	html := ""
+	for _, fc := range s.fileContainers {
+		if fc.GetPath() == filepath.Join(s.config.Deploy.TargetDir, "blog", "post-0") {
+			html = fc.GetDataAsString()
+		}
+	}
+	for _, link := range []string{
+		`<p class="tags"><a href="https://drewing.de/blog/categories/code/" class="category">code</a>`,
+		`href="https://drewing.de/blog/tags/go/"`,
+		`href="https://drewing.de/blog/tags/static/"`} {
+		if !strings.Contains(html, link) {
+			t.Error("Expected", link, "in", html)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Go":             "go",
+		"blog post":      "blog-post",
+		" Comic, Ärger!": "comic-ärger"}
+	for name, expected := range cases {
+		if actual := slugify(name); actual != expected {
+			t.Error("Expected", expected, ", but got", actual)
+		}
+	}
+}
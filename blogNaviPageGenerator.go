@@ -1,6 +1,7 @@
 package main
 
 import (
+	"path"
 	"strconv"
 
 	"github.com/ingmardrewing/staticIntf"
@@ -20,13 +21,26 @@ func NewBlogNaviPageGenerator(
 }
 
 type blogNaviPageGenerator struct {
-	pages     []staticIntf.Page
-	site      staticIntf.Site
-	path      string
-	container staticIntf.PagesContainer
+	title      string
+	pageSize   int
+	pagination string
+	pages      []staticIntf.Page
+	site       staticIntf.Site
+	path       string
+	container  staticIntf.PagesContainer
+}
+
+// Applies the pagination settings of a source
+func (n *blogNaviPageGenerator) useSettings(settings srcSettings) {
+	n.pageSize = settings.PageSize
+	n.pagination = settings.Pagination
 }
 
 func (n *blogNaviPageGenerator) Createpages() []staticIntf.Page {
+	if n.pagination == paginationStable {
+		return n.createStablePages()
+	}
+
 	bundles := n.generateBundles()
 	last := len(bundles) - 1
 	naviPages := make([]staticIntf.Page, 0)
@@ -39,23 +53,69 @@ func (n *blogNaviPageGenerator) Createpages() []staticIntf.Page {
 		if i == last {
 			filename = "index.html"
 		}
+		naviPages = append(naviPages, n.makePage(n.path, filename, bundle))
+	}
+
+	return linkNaviPages(naviPages)
+}
+
+// Creates the navi pages page/1/ … page/N/, counted from
+// the oldest posts, so the posts of a full navi page never
+// move to another one. The newest navi page is written to
+// index.html as well.
+func (n *blogNaviPageGenerator) createStablePages() []staticIntf.Page {
+	naviPages := make([]staticIntf.Page, 0)
+	if n.site == nil {
+		return naviPages
+	}
 
-		pm := staticModel.NewPageMaker()
-		pm.Title(n.site.Domain() + " Overview")
-		pm.Category("blog post navi")
-		pm.PathFromDocRoot(n.path)
-		pm.FileName(filename)
-		pm.Site(n.site)
-		pm.NavigatedPages(bundle...)
+	bundles := [][]staticIntf.Page{}
+	b := newElementBundle(n.pageSize)
+	for _, p := range n.container.Pages() {
+		b.addElement(p)
+		if b.full() {
+			bundles = append(bundles, reversePages(b.getElements()))
+			b = newElementBundle(n.pageSize)
+		}
+	}
+	if len(b.getElements()) > 0 || len(bundles) == 0 {
+		bundles = append(bundles, reversePages(b.getElements()))
+	}
 
-		naviPages = append(naviPages, pm.Make())
+	for i, bundle := range bundles {
+		pth := path.Join(n.path, "page", strconv.Itoa(i+1))
+		naviPages = append(naviPages, n.makePage(pth, "index.html", bundle))
 	}
+	naviPages = linkNaviPages(naviPages)
 
-	return naviPages
+	newest := &naviPage{Page: n.makePage(n.path, "index.html", bundles[len(bundles)-1])}
+	if len(naviPages) > 1 {
+		newest.prev = naviPages[len(naviPages)-2]
+	}
+	return append(naviPages, newest)
+}
+
+func (n *blogNaviPageGenerator) makePage(pth, filename string, bundle []staticIntf.Page) staticIntf.Page {
+	title := n.title
+	if title == "" {
+		title = n.site.Domain() + " Overview"
+	}
+
+	pm := staticModel.NewPageMaker()
+	pm.Title(title)
+	pm.Category("blog post navi")
+	pm.PathFromDocRoot(pth)
+	pm.FileName(filename)
+	pm.Site(n.site)
+	pm.NavigatedPages(bundle...)
+	return pm.Make()
 }
 
 func (n *blogNaviPageGenerator) getReversedPages() []staticIntf.Page {
-	pages := n.container.Pages()
+	return reversePages(n.container.Pages())
+}
+
+func reversePages(pages []staticIntf.Page) []staticIntf.Page {
 	length := len(pages)
 	reversed := make([]staticIntf.Page, 0)
 	for i := length - 1; i >= 0; i-- {
@@ -76,13 +136,13 @@ func (n *blogNaviPageGenerator) generateBundles() [][]staticIntf.Page {
 
 func (n *blogNaviPageGenerator) generateReversedBundles() []*elementBundle {
 	reversed := n.getReversedPages()
-	b := newElementBundle()
+	b := newElementBundle(n.pageSize)
 	bundles := []*elementBundle{}
 	for _, p := range reversed {
 		b.addElement(p)
 		if b.full() {
 			bundles = append(bundles, b)
-			b = newElementBundle()
+			b = newElementBundle(n.pageSize)
 		}
 	}
 	if !b.full() {
@@ -90,3 +150,36 @@ func (n *blogNaviPageGenerator) generateReversedBundles() []*elementBundle {
 	}
 	return bundles
 }
+
+// A navi page knowing its neighbours, which templates
+// can link as rel=prev and rel=next via .Prev and .Next.
+// Prev holds older posts, both are nil at the ends.
+type naviPage struct {
+	staticIntf.Page
+	prev staticIntf.Page
+	next staticIntf.Page
+}
+
+func (p *naviPage) Prev() staticIntf.Page { return p.prev }
+
+func (p *naviPage) Next() staticIntf.Page { return p.next }
+
+// Wraps the navi pages, ordered from the oldest
+// to the newest posts, to link their neighbours
+func linkNaviPages(pages []staticIntf.Page) []staticIntf.Page {
+	linked := []*naviPage{}
+	for _, p := range pages {
+		linked = append(linked, &naviPage{Page: p})
+	}
+	result := []staticIntf.Page{}
+	for i, p := range linked {
+		if i > 0 {
+			p.prev = linked[i-1]
+		}
+		if i < len(linked)-1 {
+			p.next = linked[i+1]
+		}
+		result = append(result, p)
+	}
+	return result
+}
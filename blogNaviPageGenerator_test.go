@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/staticIntf"
+	"github.com/ingmardrewing/staticModel"
+)
+
+func givenSite() staticIntf.Site {
+	return staticModel.NewSiteDto("", "", "", "drewing.de", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "")
+}
+
+func givenPostsContainer(n int) staticIntf.PagesContainer {
+	c := staticModel.NewPagesContainer(staticIntf.BLOG, "Blog")
+	site := givenSite()
+	for i := 0; i < n; i++ {
+		pm := staticModel.NewPageMaker()
+		pm.Title(fmt.Sprintf("Post %d", i))
+		pm.PathFromDocRoot(fmt.Sprintf("/blog/post-%d/", i))
+		pm.FileName("index.html")
+		pm.Site(site)
+		c.AddPage(pm.Make())
+	}
+	return c
+}
+
+func givenNaviPageGenerator(posts, pageSize int, pagination string) *blogNaviPageGenerator {
+	g := NewBlogNaviPageGenerator(givenSite(), "blog", givenPostsContainer(posts))
+	g.useSettings(srcSettings{PageSize: pageSize, Pagination: pagination})
+	return g
+}
+
+func locations(pages []staticIntf.Page) []string {
+	l := []string{}
+	for _, p := range pages {
+		l = append(l, p.PathFromDocRoot()+"/"+p.HtmlFilename())
+	}
+	return l
+}
+
+func TestNaviPagesWithPageSize(t *testing.T) {
+	pages := givenNaviPageGenerator(7, 3, "").Createpages()
+
+	expected := fmt.Sprint([]string{"blog/index0.html", "blog/index1.html", "blog/index.html"})
+	if actual := fmt.Sprint(locations(pages)); actual != expected {
+		t.Error("Expected", expected, ", but got", actual)
+	}
+	if n := len(pages[0].NavigatedPages()); n != 1 {
+		t.Error("Expected the oldest navi page to hold the rest of 1 post, but got", n)
+	}
+}
+
+func TestStableNaviPages(t *testing.T) {
+	pages := givenNaviPageGenerator(7, 3, paginationStable).Createpages()
+
+	expected := fmt.Sprint([]string{"blog/page/1/index.html", "blog/page/2/index.html",
+		"blog/page/3/index.html", "blog/index.html"})
+	if actual := fmt.Sprint(locations(pages)); actual != expected {
+		t.Error("Expected", expected, ", but got", actual)
+	}
+
+	first := pages[0].NavigatedPages()
+	if len(first) != 3 || first[0].Title() != "Post 2" || first[2].Title() != "Post 0" {
+		t.Error("Expected page 1 to hold posts 2 to 0, but got", locations(first))
+	}
+	if n := len(pages[3].NavigatedPages()); n != 1 {
+		t.Error("Expected index.html to hold the newest post only, but got", n)
+	}
+
+	// adding posts doesn't move the posts of a full page
+	more := givenNaviPageGenerator(10, 3, paginationStable).Createpages()
+	if more[1].NavigatedPages()[0].Title() != pages[1].NavigatedPages()[0].Title() {
+		t.Error("Expected page 2 to keep its posts")
+	}
+}
+
+func TestNaviPagesLinkNeighbours(t *testing.T) {
+	pages := givenNaviPageGenerator(7, 3, paginationStable).Createpages()
+
+	second := pages[1].(*naviPage)
+	if second.Prev() != pages[0] || second.Next() != pages[2] {
+		t.Error("Expected page 2 to link pages 1 and 3")
+	}
+	if pages[0].(*naviPage).Prev() != nil {
+		t.Error("Expected no prev page for page 1")
+	}
+	newest := pages[3].(*naviPage)
+	if newest.Prev() != pages[1] || newest.Next() != nil {
+		t.Error("Expected index.html to link page 2 as prev only")
+	}
+}
+
+func TestNaviPagesRenderNeighbourLinks(t *testing.T) {
+	s := givenRenderedSite(t, func(posts string) string {
+		return fmt.Sprintf(`[{
+			"domain": "drewing.de",
+			"deploy": {"targetDir": "deploy"},
+			"src": [{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog", "pageSize": 1}]
+		}]`, posts)
+	})
+
+	files := renderedFiles(s, nil)
+	naviPages := s.sources[0].Container().NaviPages()
+	if len(naviPages) < 3 {
+		t.Fatal("Expected several navi pages, but got", locations(naviPages))
+	}
+	middle := naviPages[1].(*naviPage)
+	html := files["deploy/"+strings.TrimPrefix(middle.PathFromDocRoot(), "/")+"/"+middle.HtmlFilename()]
+	for _, link := range []string{
+		`<link rel="prev" href="` + middle.Prev().Url() + `">`,
+		`<link rel="next" href="` + middle.Next().Url() + `">`,
+		`<a rel="prev" href="` + middle.Prev().Url() + `">older</a>`,
+		`<a rel="next" href="` + middle.Next().Url() + `">newer</a></nav>`} {
+		if !strings.Contains(html, link) {
+			t.Error("Expected", link, "in", html)
+		}
+	}
+	newest := files["deploy/blog/index.html"]
+	if !strings.Contains(newest, `rel="prev"`) || strings.Contains(newest, `rel="next"`) {
+		t.Error("Expected the newest navi page to link the older one only, but got", newest)
+	}
+}
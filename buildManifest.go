@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ingmardrewing/fs"
+)
+
+// Creates a new, empty buildManifest for the
+// given deploy dir
+func NewBuildManifest(targetDir string) *buildManifest {
+	m := new(buildManifest)
+	m.targetDir = targetDir
+	m.path = manifestPath(targetDir)
+	m.Files = map[string]string{}
+	return m
+}
+
+// Reads the manifest of the last build stored next
+// to the given deploy dir. Returns an empty manifest
+// if there was no build before.
+func ReadBuildManifest(targetDir string) (*buildManifest, error) {
+	return readManifest(targetDir, manifestPath(targetDir))
+}
+
+// Reads the manifest of the files last uploaded
+// from the given deploy dir
+func ReadDeployedManifest(targetDir string) (*buildManifest, error) {
+	return readManifest(targetDir, deployedManifestPath(targetDir))
+}
+
+func readManifest(targetDir, manifestFile string) (*buildManifest, error) {
+	m := NewBuildManifest(targetDir)
+	m.path = manifestFile
+	data, err := ioutil.ReadFile(manifestFile)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(data, m)
+	return m, err
+}
+
+// Returns the path of the manifest file, which
+// is located next to the deploy dir
+func manifestPath(targetDir string) string {
+	return filepath.Clean(targetDir) + ".manifest.json"
+}
+
+// Returns the path of the manifest describing
+// the state of the last upload
+func deployedManifestPath(targetDir string) string {
+	return filepath.Clean(targetDir) + ".deployed.json"
+}
+
+// Returns the content hash used to detect changes
+// of a file between two builds
+func contentHash(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// The buildManifest maps the path of each output
+// file, relative to the deploy dir, to the hash
+// of its content. It also records which files
+// have been added, changed or removed by the build.
+type buildManifest struct {
+	targetDir string
+	path      string
+	Files     map[string]string `json:"files"`
+	Added     []string          `json:"added"`
+	Changed   []string          `json:"changed"`
+	Removed   []string          `json:"removed"`
+	Scheduled []string          `json:"scheduled,omitempty"`
+	Drafts    bool              `json:"drafts,omitempty"`
+}
+
+// Returns the path of the given file container
+// relative to the deploy dir
+func (m *buildManifest) relPath(fc fs.FileContainer) string {
+	full := filepath.Join(fc.GetPath(), fc.GetFilename())
+	rel, err := filepath.Rel(m.targetDir, full)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(full)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Returns the absolute path of a file listed
+// in the manifest
+func (m *buildManifest) fullPath(rel string) string {
+	return filepath.Join(m.targetDir, filepath.FromSlash(rel))
+}
+
+// Adds a file with the given hash, compares it to
+// the given manifest of the last build and returns
+// true if the file needs to be written
+func (m *buildManifest) add(rel, hash string, last *buildManifest) bool {
+	m.Files[rel] = hash
+	lastHash, known := last.Files[rel]
+	if !known {
+		m.Added = append(m.Added, rel)
+		return true
+	}
+	if lastHash != hash {
+		m.Changed = append(m.Changed, rel)
+		return true
+	}
+	exists, _ := fs.PathExists(m.fullPath(rel))
+	return !exists
+}
+
+// Records all files of the last build which
+// haven't been produced by this build
+func (m *buildManifest) collectRemoved(last *buildManifest) {
+	for rel := range last.Files {
+		if _, ok := m.Files[rel]; !ok {
+			m.Removed = append(m.Removed, rel)
+		}
+	}
+	sort.Strings(m.Added)
+	sort.Strings(m.Changed)
+	sort.Strings(m.Removed)
+}
+
+// Deletes the files of the last build which
+// haven't been produced by this build
+func (m *buildManifest) prune() error {
+	for _, rel := range m.Removed {
+		err := os.Remove(m.fullPath(rel))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes the manifest next to the deploy dir
+func (m *buildManifest) Write() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(m.path, data, 0644)
+}
+
+// Returns the files which differ from the given
+// manifest of an earlier state and the files which
+// don't exist anymore, both sorted
+func (m *buildManifest) diff(earlier *buildManifest) ([]string, []string) {
+	modified := []string{}
+	for rel, hash := range m.Files {
+		if earlier.Files[rel] != hash {
+			modified = append(modified, rel)
+		}
+	}
+	removed := []string{}
+	for rel := range earlier.Files {
+		if _, ok := m.Files[rel]; !ok {
+			removed = append(removed, rel)
+		}
+	}
+	sort.Strings(modified)
+	sort.Strings(removed)
+	return modified, removed
+}
+
+// Returns a human readable summary of the build
+func (m *buildManifest) Summary(pruned bool) string {
+	unchanged := len(m.Files) - len(m.Added) - len(m.Changed)
+	summary := fmt.Sprintf("%d added, %d changed, %d removed, %d unchanged",
+		len(m.Added), len(m.Changed), len(m.Removed), unchanged)
+	for _, rel := range m.Removed {
+		if pruned {
+			summary += "\n  deleted: " + rel
+		} else {
+			summary += "\n  stale:   " + rel
+		}
+	}
+	return summary
+}
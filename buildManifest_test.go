@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ingmardrewing/fs"
+)
+
+func TestBuildManifestDiff(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "manifest")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "same.html"), []byte("same"), 0644)
+
+	last := NewBuildManifest(dir)
+	last.Files["same.html"] = contentHash("same")
+	last.Files["changed.html"] = contentHash("old")
+	last.Files["removed.html"] = contentHash("gone")
+
+	m := NewBuildManifest(dir)
+	if m.add("same.html", contentHash("same"), last) {
+		t.Error("Expected unchanged existing file not to be written")
+	}
+	if !m.add("changed.html", contentHash("new"), last) {
+		t.Error("Expected changed file to be written")
+	}
+	if !m.add("added.html", contentHash("added"), last) {
+		t.Error("Expected added file to be written")
+	}
+	m.collectRemoved(last)
+
+	expected := "1 added, 1 changed, 1 removed, 1 unchanged\n  stale:   removed.html"
+	actual := m.Summary(false)
+	if actual != expected {
+		t.Error("Expected", expected, ", but got", actual)
+	}
+}
+
+func TestBuildManifestReadWrite(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "manifest")
+	defer os.RemoveAll(dir)
+	targetDir := filepath.Join(dir, "deploy")
+	defer os.Remove(manifestPath(targetDir))
+
+	m := NewBuildManifest(targetDir)
+	fc := fs.NewFileContainer()
+	fc.SetPath(filepath.Join(targetDir, "blog"))
+	fc.SetFilename("index.html")
+	rel := m.relPath(fc)
+	if rel != "blog/index.html" {
+		t.Error("Expected blog/index.html, but got", rel)
+	}
+	m.add(rel, contentHash("content"), NewBuildManifest(targetDir))
+	m.Write()
+
+	read, err := ReadBuildManifest(targetDir)
+	if err != nil {
+		t.Error(err)
+	}
+	if read.Files[rel] != contentHash("content") {
+		t.Error("Expected hash of", rel, "to be read from manifest")
+	}
+}
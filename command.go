@@ -1,21 +1,56 @@
 package main
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 func newCommand(name string, args ...string) *command {
 	c := new(command)
 	c.name = name
 	c.setArgs(args...)
+	c.logger = log.StandardLogger()
 	return c
 }
 
 type command struct {
 	name      string
 	arguments []string
+	env       []string
+	timeout   time.Duration
+	stdout    string
+	stderr    string
+
+	// logs the output while the command runs
+	logger *log.Logger
+}
+
+// Error returned if a command couldn't be started,
+// exited with a non-zero exit code or timed out
+type commandError struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+	Duration time.Duration
+	Err      error
+}
+
+func (e *commandError) Error() string {
+	msg := fmt.Sprintf("%s failed after %s with exit code %d: %v",
+		e.Command, e.Duration.Round(time.Millisecond), e.ExitCode, e.Err)
+	if e.Stderr != "" {
+		msg += "\n" + e.Stderr
+	}
+	return msg
 }
 
 func (c *command) setArgs(args ...string) {
@@ -24,12 +59,97 @@ func (c *command) setArgs(args ...string) {
 	}
 }
 
-func (c *command) run() {
-	command := exec.Command(c.name, c.arguments...)
+// Overrides or adds an environment variable
+// for the command
+func (c *command) setEnv(key, value string) {
+	c.env = append(c.env, key+"="+value)
+}
+
+// Kills the command if it runs longer than
+// the given duration, zero means no timeout
+func (c *command) setTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// Runs the command, streaming its output to the log,
+// the standard output at info and the standard error
+// at error level. The captured output is available
+// afterwards via output().
+func (c *command) run() error {
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, c.name, c.arguments...)
+	if len(c.env) > 0 {
+		cmd.Env = append(os.Environ(), c.env...)
+	}
+
+	entry := c.logger.WithField("command", c.name)
+	out, errOut := &logWriter{log: entry.Info}, &logWriter{log: entry.Error}
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd.Stdout = io.MultiWriter(stdout, out)
+	cmd.Stderr = io.MultiWriter(stderr, errOut)
+
+	start := time.Now()
+	err := cmd.Run()
+	out.Close()
+	errOut.Close()
+	c.stdout = stdout.String()
+	c.stderr = stderr.String()
+	if err == nil {
+		return nil
+	}
+
+	cmdErr := &commandError{
+		Command:  strings.TrimSpace(c.name + " " + strings.Join(c.arguments, " ")),
+		ExitCode: -1,
+		Stderr:   strings.TrimSpace(c.stderr),
+		Duration: time.Since(start),
+		Err:      err}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			cmdErr.ExitCode = status.ExitStatus()
+		}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		cmdErr.Err = fmt.Errorf("timed out after %s", c.timeout)
+	}
+	return cmdErr
+}
+
+// Returns the captured standard output
+// of the last run
+func (c *command) output() string {
+	return c.stdout
+}
+
+// Logs each line written to it, the last
+// one without line break on Close
+type logWriter struct {
+	log     func(args ...interface{})
+	partial []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.log(strings.TrimSuffix(string(w.partial[:i]), "\r"))
+		w.partial = w.partial[i+1:]
+	}
+}
 
-	err := command.Run()
-	if err != nil {
-		log.Println(c.name, strings.Join(c.arguments, " "))
-		log.Fatalln(err)
+func (w *logWriter) Close() error {
+	if len(w.partial) > 0 {
+		w.log(string(w.partial))
+		w.partial = nil
 	}
+	return nil
 }
@@ -1,6 +1,22 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Logs the output of the command into the returned buffer
+func givenCommandLog(c *command) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	c.logger = log.New()
+	c.logger.Out = buf
+	c.logger.Formatter = &log.TextFormatter{DisableTimestamp: true}
+	return buf
+}
 
 func TestNewCommand(t *testing.T) {
 	c := newCommand("testCommand", "arg1", "arg2")
@@ -13,3 +29,50 @@ func TestNewCommand(t *testing.T) {
 		t.Error("Expected", c.arguments, "to be arg1 and arg2")
 	}
 }
+
+func TestCommandRunCapturesOutputAndEnv(t *testing.T) {
+	c := newCommand("sh", "-c", "echo $GREETING")
+	c.setEnv("GREETING", "hello")
+	out := givenCommandLog(c)
+
+	err := c.run()
+	if err != nil {
+		t.Error("Expected no error, but got", err)
+	}
+	if c.output() != "hello\n" {
+		t.Error("Expected output hello, but got", c.output())
+	}
+	if !strings.Contains(out.String(), "level=info msg=hello command=sh") {
+		t.Error("Expected output to be logged, but got", out.String())
+	}
+}
+
+func TestCommandRunReturnsCommandError(t *testing.T) {
+	c := newCommand("sh", "-c", "echo broken >&2; exit 3")
+	errOut := givenCommandLog(c)
+
+	err := c.run()
+	cmdErr, ok := err.(*commandError)
+	if !ok {
+		t.Fatal("Expected commandError, but got", err)
+	}
+	if cmdErr.ExitCode != 3 {
+		t.Error("Expected exit code 3, but got", cmdErr.ExitCode)
+	}
+	if cmdErr.Stderr != "broken" {
+		t.Error("Expected stderr broken, but got", cmdErr.Stderr)
+	}
+	if !strings.Contains(errOut.String(), "level=error msg=broken command=sh") {
+		t.Error("Expected stderr to be logged, but got", errOut.String())
+	}
+}
+
+func TestCommandRunTimeout(t *testing.T) {
+	c := newCommand("sleep", "5")
+	c.setTimeout(50 * time.Millisecond)
+
+	err := c.run()
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Error("Expected timeout error, but got", err)
+	}
+}
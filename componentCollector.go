@@ -1,8 +1,6 @@
 package main
 
 import (
-	"reflect"
-
 	"github.com/ingmardrewing/staticIntf"
 )
 
@@ -28,7 +26,7 @@ func (c *componentCollector) AddComponents(comps []staticIntf.Component) {
 
 func (c *componentCollector) componentExists(givenComp staticIntf.Component) bool {
 	for _, comp := range c.components {
-		if reflect.TypeOf(comp) == reflect.TypeOf(givenComp) {
+		if identity(comp) == identity(givenComp) {
 			return true
 		}
 	}
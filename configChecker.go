@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+// Creates a configChecker validating the given
+// configs, which have been read from the given file
+func NewConfigChecker(file string, configs []staticPersistence.Config) *configChecker {
+	c := new(configChecker)
+	c.file = file
+	c.configs = configs
+	return c
+}
+
+// A problem within the config, located by
+// the json path of the offending field
+type configProblem struct {
+	path string
+	msg  string
+}
+
+func (p configProblem) String() string {
+	return p.path + ": " + p.msg
+}
+
+// The configChecker validates all fields the site
+// creation depends on and the source dirs referenced
+// by the config
+type configChecker struct {
+	file     string
+	configs  []staticPersistence.Config
+	settings []siteSettings
+	problems []configProblem
+}
+
+// Runs all checks and returns the problems found
+func (c *configChecker) Check() []configProblem {
+	c.problems = nil
+	if !c.checkSyntax() {
+		return c.problems
+	}
+	if len(c.configs) == 0 {
+		c.report("", "no site configured")
+	}
+	for i, config := range c.configs {
+		c.checkSite(fmt.Sprintf("[%d]", i), config, settingsAt(c.settings, i))
+	}
+	return c.problems
+}
+
+func (c *configChecker) report(jsonPath, msg string, args ...interface{}) {
+	c.problems = append(c.problems, configProblem{
+		path.Base(c.file) + jsonPath,
+		fmt.Sprintf(msg, args...)})
+}
+
+// Checks that the config file is valid json,
+// the other checks are pointless otherwise
+func (c *configChecker) checkSyntax() bool {
+	data, err := ioutil.ReadFile(c.file)
+	if err != nil {
+		c.report("", "%v", err)
+		return false
+	}
+	sites := []map[string]interface{}{}
+	if err := json.Unmarshal(data, &sites); err != nil {
+		c.report("", "%s", jsonErrorPosition(data, err))
+		return false
+	}
+	if err := json.Unmarshal(data, &c.settings); err != nil {
+		c.report("", "%s", jsonErrorPosition(data, err))
+		return false
+	}
+	return true
+}
+
+func (c *configChecker) checkSite(p string, config staticPersistence.Config, settings siteSettings) {
+	if strings.TrimSpace(config.Domain) == "" {
+		c.report(p+".domain", "must not be empty")
+	} else if strings.Contains(config.Domain, "/") {
+		c.report(p+".domain", "must be a plain domain name without scheme or path, got %q", config.Domain)
+	}
+
+	if config.Deploy.TargetDir == "" {
+		c.report(p+".deploy.targetDir", "must not be empty")
+	}
+	if config.Deploy.CssFileName == "" {
+		c.report(p+".deploy.cssFileName", "must not be empty")
+	}
+	if (config.Deploy.RssPath == "") != (config.Deploy.RssFilename == "") {
+		c.report(p+".deploy", "rssPath and rssFilename must be set both or not at all")
+	}
+
+	for i, l := range config.Context.MainLinks {
+		c.checkLink(fmt.Sprintf("%s.context.mainLinks[%d]", p, i),
+			l.Label, l.Path, l.FileName, l.ExternalLink)
+	}
+	for i, l := range config.Context.MarginalLinks {
+		c.checkLink(fmt.Sprintf("%s.context.marginalLinks[%d]", p, i),
+			l.Label, l.Path, l.FileName, l.ExternalLink)
+	}
+
+	for i, exclude := range settings.Sitemap.Exclude {
+		if !strings.HasPrefix(exclude, "/") {
+			c.report(fmt.Sprintf("%s.sitemap.exclude[%d]", p, i),
+				"must be a path from the doc root starting with /, got %q", exclude)
+		}
+	}
+
+	if settings.Templates != "" {
+		if _, err := NewPageTemplates(settings.Templates, "", ""); err != nil {
+			c.report(p+".templates", "%v", err)
+		}
+	}
+
+	if settings.Theme != "" {
+		if _, err := LoadTheme(settings.ThemesDir, settings.Theme); err != nil {
+			c.report(p+".theme", "%v", err)
+		}
+	}
+
+	if settings.Static != "" {
+		if info, err := os.Stat(settings.Static); err != nil {
+			c.report(p+".static", "%v", err)
+		} else if !info.IsDir() {
+			c.report(p+".static", "%s is not a directory", settings.Static)
+		}
+	}
+
+	if len(config.Src) == 0 {
+		c.report(p+".src", "no sources configured, the site will be empty")
+	}
+	for i, src := range config.Src {
+		srcPath := fmt.Sprintf("%s.src[%d]", p, i)
+		c.checkSource(srcPath, src.Type, src.Dir)
+		c.checkSourceSettings(srcPath, settings.srcAt(i))
+	}
+}
+
+func (c *configChecker) checkSourceSettings(p string, settings srcSettings) {
+	if settings.PageSize < 0 {
+		c.report(p+".pageSize", "must not be negative, got %d", settings.PageSize)
+	}
+	switch settings.Pagination {
+	case "", paginationIndex, paginationStable:
+	default:
+		c.report(p+".pagination", "must be %q or %q, got %q",
+			paginationIndex, paginationStable, settings.Pagination)
+	}
+
+	rep := settings.Representationals
+	switch rep.Strategy {
+	case "", pickLatest, pickFeatured, pickRandom:
+	case pickTag:
+		if rep.Tag == "" {
+			c.report(p+".representationals.tag", "must not be empty for the strategy %q", pickTag)
+		}
+	default:
+		c.report(p+".representationals.strategy", "must be one of %q, %q, %q or %q, got %q",
+			pickLatest, pickFeatured, pickRandom, pickTag, rep.Strategy)
+	}
+
+	switch settings.Feed.Content {
+	case "", feedFull, feedExcerpt:
+	default:
+		c.report(p+".feed.content", "must be %q or %q, got %q",
+			feedFull, feedExcerpt, settings.Feed.Content)
+	}
+	if settings.Feed.Limit < -1 {
+		c.report(p+".feed.limit", "must be -1 or more, got %d", settings.Feed.Limit)
+	}
+
+	for i, name := range settings.Assets {
+		clean := filepath.Clean(name)
+		if name == "" || filepath.IsAbs(name) || clean == "." || strings.HasPrefix(clean, "..") {
+			c.report(fmt.Sprintf("%s.assets[%d]", p, i),
+				"must be the name of a dir within the source dir, got %q", name)
+		}
+	}
+}
+
+func (c *configChecker) checkLink(p, label, pth, fileName, externalLink string) {
+	if label == "" {
+		c.report(p+".label", "must not be empty")
+	}
+	if externalLink == "" && pth == "" && fileName == "" {
+		c.report(p, "needs either externalLink or path and fileName")
+	}
+}
+
+func (c *configChecker) checkSource(p, variant, dir string) {
+	if variant == "" {
+		c.report(p+".type", "must not be empty, registered types are: %s",
+			strings.Join(RegisteredSourceVariants(), ", "))
+	} else if !isRegisteredSource(variant) {
+		c.report(p+".type", "unknown source type %q, registered types are: %s",
+			variant, strings.Join(RegisteredSourceVariants(), ", "))
+	}
+
+	if dir == "" {
+		c.report(p+".dir", "must not be empty")
+		return
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		c.report(p+".dir", "%v", err)
+		return
+	}
+	if !info.IsDir() {
+		c.report(p+".dir", "%s is not a directory", dir)
+		return
+	}
+
+	docs, errs := readPageDocs(dir)
+	if len(docs) == 0 && len(errs) == 0 {
+		c.report(p+".dir", "%s contains no page json or markdown files", dir)
+	}
+	for _, err := range errs {
+		c.report(p+".dir", "%v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+func TestConfigCheckerAcceptsTestConfig(t *testing.T) {
+	checker := NewConfigChecker("testResources/configNew.json", conf)
+
+	problems := checker.Check()
+
+	if len(problems) != 0 {
+		t.Error("Expected no problems, but got", problems)
+	}
+}
+
+func TestConfigCheckerReportsJsonPaths(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "check")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte(`[{
+		"domain": "",
+		"src": [
+			{"dir": "testResources/src/posts/", "type": "blgo", "pageSize": -1, "pagination": "newest",
+				"assets": ["downloads", "../pages"]},
+			{"dir": "testResources/src/missing/", "type": "blog", "representationals": {"strategy": "tag"},
+				"feed": {"content": "summary"}}
+		],
+		"deploy": {"cssFileName": "styles.css"},
+		"sitemap": {"exclude": ["drafts/"]},
+		"static": "testResources/static/"
+	}]`), 0644)
+	configs := staticPersistence.ReadConfig(dir, "broken.json")
+	checker := NewConfigChecker(filepath.Join(dir, "broken.json"), configs)
+
+	problems := []string{}
+	for _, p := range checker.Check() {
+		problems = append(problems, p.String())
+	}
+	actual := strings.Join(problems, "\n")
+
+	for _, expected := range []string{
+		"broken.json[0].domain: must not be empty",
+		"broken.json[0].deploy.targetDir: must not be empty",
+		`broken.json[0].src[0].type: unknown source type "blgo"`,
+		"broken.json[0].src[0].pageSize: must not be negative",
+		`broken.json[0].src[0].pagination: must be "index" or "stable", got "newest"`,
+		"broken.json[0].src[1].dir: ",
+		`broken.json[0].src[1].representationals.tag: must not be empty for the strategy "tag"`,
+		`broken.json[0].src[1].feed.content: must be "full" or "excerpt", got "summary"`,
+		`broken.json[0].sitemap.exclude[0]: must be a path from the doc root starting with /, got "drafts/"`,
+		`broken.json[0].src[0].assets[1]: must be the name of a dir within the source dir, got "../pages"`,
+		"broken.json[0].static: "} {
+		if !strings.Contains(actual, expected) {
+			t.Error("Expected problem", expected, ", but got", actual)
+		}
+	}
+}
+
+func TestConfigCheckerReportsSyntaxErrors(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "check")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte("[{\n\"domain\": \"x\",,}]"), 0644)
+	checker := NewConfigChecker(filepath.Join(dir, "broken.json"), nil)
+
+	problems := checker.Check()
+
+	if len(problems) != 1 || !strings.Contains(problems[0].String(), "line 2") {
+		t.Error("Expected syntax error in line 2, but got", problems)
+	}
+}
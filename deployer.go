@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// A deployer transfers files from the local
+// deploy dir to the remote web server
+type deployer interface {
+	Connect() error
+	Upload(localPath, remotePath string) error
+	Remove(remotePath string) error
+	Close() error
+}
+
+// A deployer uploading many files at once, returning
+// the files it uploaded, even if it failed partway
+type batchDeployer interface {
+	UploadAll(localDir, remoteDir string, rels []string) ([]string, error)
+}
+
+// Creates the deployer for the protocol given
+// in the upload settings
+func NewDeployer(settings uploadSettings) (deployer, error) {
+	switch settings.Protocol {
+	case "sftp":
+		return NewSftpDeployer(settings), nil
+	case "ftp":
+		return NewFtpDeployer(settings, false), nil
+	case "ftps":
+		return NewFtpDeployer(settings, true), nil
+	case "rsync":
+		return NewRsyncDeployer(settings), nil
+	case "":
+		return nil, errors.New("no upload protocol configured")
+	}
+	return nil, fmt.Errorf("unknown upload protocol: %s", settings.Protocol)
+}
+
+// Creates a new deployment of the files in the
+// given deploy dir using the given deployer
+func NewDeployment(targetDir, remoteDir string, d deployer) *deployment {
+	dp := new(deployment)
+	dp.targetDir = targetDir
+	dp.remoteDir = remoteDir
+	dp.deployer = d
+	dp.out = os.Stdout
+	return dp
+}
+
+// A deployment uploads the files which changed
+// since the last upload, according to the build
+// manifest, and removes the files which don't
+// exist anymore
+type deployment struct {
+	targetDir string
+	remoteDir string
+	deployer  deployer
+	dryRun    bool
+	out       io.Writer
+}
+
+// Uploads the changed files and records the
+// uploaded state next to the deploy dir
+func (dp *deployment) Run() error {
+	built, err := ReadBuildManifest(dp.targetDir)
+	if err != nil {
+		return err
+	}
+	if len(built.Files) == 0 {
+		return errors.New("no build found in " + dp.targetDir + ", run -make first")
+	}
+	if built.Drafts {
+		return errors.New("the build in " + dp.targetDir + " includes drafts, run -make without -drafts first")
+	}
+	deployed, err := ReadDeployedManifest(dp.targetDir)
+	if err != nil {
+		return err
+	}
+
+	uploads, removals := built.diff(deployed)
+	total := len(uploads) + len(removals)
+	if total == 0 {
+		fmt.Fprintln(dp.out, "Nothing to deploy")
+		return nil
+	}
+
+	if !dp.dryRun {
+		if err := dp.deployer.Connect(); err != nil {
+			return err
+		}
+		defer dp.deployer.Close()
+	}
+
+	done := 0
+	if b, ok := dp.deployer.(batchDeployer); ok && !dp.dryRun {
+		uploaded, err := b.UploadAll(dp.targetDir, dp.remoteDir, uploads)
+		if err != nil {
+			for _, rel := range uploaded {
+				deployed.Files[rel] = built.Files[rel]
+			}
+			return dp.failed(deployed, err)
+		}
+		for _, rel := range uploads {
+			done++
+			dp.report(done, total, "upload", rel)
+			deployed.Files[rel] = built.Files[rel]
+		}
+		uploads = nil
+	}
+	for _, rel := range uploads {
+		done++
+		dp.report(done, total, "upload", rel)
+		if dp.dryRun {
+			continue
+		}
+		local := filepath.Join(dp.targetDir, filepath.FromSlash(rel))
+		if err := dp.deployer.Upload(local, path.Join(dp.remoteDir, rel)); err != nil {
+			return dp.failed(deployed, err)
+		}
+		deployed.Files[rel] = built.Files[rel]
+	}
+	for _, rel := range removals {
+		done++
+		dp.report(done, total, "remove", rel)
+		if dp.dryRun {
+			continue
+		}
+		if err := dp.deployer.Remove(path.Join(dp.remoteDir, rel)); err != nil {
+			log.Warn("deployment.Run() - ", err)
+		}
+		delete(deployed.Files, rel)
+	}
+
+	if dp.dryRun {
+		return nil
+	}
+	return deployed.Write()
+}
+
+// Records the files uploaded before the deployment failed,
+// so the next deployment continues where this one stopped
+func (dp *deployment) failed(deployed *buildManifest, err error) error {
+	if werr := deployed.Write(); werr != nil {
+		log.Warn("deployment.failed() - ", werr)
+	}
+	return err
+}
+
+func (dp *deployment) report(done, total int, action, rel string) {
+	prefix := ""
+	if dp.dryRun {
+		prefix = "(dry run) "
+	}
+	fmt.Fprintf(dp.out, "%s[%d/%d] %s %s\n", prefix, done, total, action, rel)
+}
+
+// Returns the given file path or, if it is
+// empty, the default file within ~/.ssh
+func sshFileOrDefault(file, defaultName string) string {
+	if file != "" {
+		return file
+	}
+	u, err := user.Current()
+	if err != nil {
+		return defaultName
+	}
+	return filepath.Join(u.HomeDir, ".ssh", defaultName)
+}
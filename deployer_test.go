@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/pkg/sftp"
+)
+
+// Creates a deploy dir with a build manifest
+// listing the given files
+func givenBuild(t *testing.T, files map[string]string) string {
+	dir, _ := ioutil.TempDir("", "deploy")
+	targetDir := filepath.Join(dir, "deploy")
+	m := NewBuildManifest(targetDir)
+	for rel, content := range files {
+		full := filepath.Join(targetDir, rel)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		ioutil.WriteFile(full, []byte(content), 0644)
+		m.Files[rel] = contentHash(content)
+	}
+	if err := m.Write(); err != nil {
+		t.Fatal(err)
+	}
+	return targetDir
+}
+
+// Connects an sftpDeployer to an in-process sftp server
+func inProcessSftpDeployer() *sftpDeployer {
+	d := NewSftpDeployer(uploadSettings{Protocol: "sftp"})
+	d.dial = func() (*sftp.Client, io.Closer, error) {
+		clientReader, serverWriter := io.Pipe()
+		serverReader, clientWriter := io.Pipe()
+		server, err := sftp.NewServer(struct {
+			io.Reader
+			io.WriteCloser
+		}{serverReader, serverWriter})
+		if err != nil {
+			return nil, nil, err
+		}
+		go func() {
+			server.Serve()
+			serverWriter.Close()
+		}()
+		client, err := sftp.NewClientPipe(clientReader, clientWriter)
+		return client, server, err
+	}
+	return d
+}
+
+func TestSftpDeployment(t *testing.T) {
+	targetDir := givenBuild(t, map[string]string{
+		"index.html":      "home",
+		"blog/index.html": "blog"})
+	defer os.RemoveAll(filepath.Dir(targetDir))
+	remoteDir := filepath.Join(filepath.Dir(targetDir), "remote")
+
+	dp := NewDeployment(targetDir, remoteDir, inProcessSftpDeployer())
+	dp.out = ioutil.Discard
+	if err := dp.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(filepath.Join(remoteDir, "blog", "index.html"))
+	if string(data) != "blog" {
+		t.Error("Expected blog/index.html to be uploaded, but got", string(data))
+	}
+
+	built, _ := ReadBuildManifest(targetDir)
+	delete(built.Files, "blog/index.html")
+	built.Write()
+
+	if err := dp.Run(); err != nil {
+		t.Fatal(err)
+	}
+	exists, _ := fs.PathExists(filepath.Join(remoteDir, "blog", "index.html"))
+	if exists {
+		t.Error("Expected removed file to be deleted remotely")
+	}
+}
+
+type fakeFtpConn struct {
+	dirs  []string
+	files map[string]string
+}
+
+func (f *fakeFtpConn) MakeDir(path string) error {
+	f.dirs = append(f.dirs, path)
+	return nil
+}
+
+func (f *fakeFtpConn) Stor(path string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	f.files[path] = string(data)
+	return err
+}
+
+func (f *fakeFtpConn) Delete(path string) error {
+	delete(f.files, path)
+	return nil
+}
+
+func (f *fakeFtpConn) Quit() error { return nil }
+
+func TestFtpDeploymentOnlyUploadsChangedFiles(t *testing.T) {
+	targetDir := givenBuild(t, map[string]string{
+		"index.html":      "home",
+		"blog/index.html": "blog"})
+	defer os.RemoveAll(filepath.Dir(targetDir))
+
+	deployed := NewBuildManifest(targetDir)
+	deployed.path = deployedManifestPath(targetDir)
+	deployed.Files["index.html"] = contentHash("home")
+	deployed.Write()
+
+	conn := &fakeFtpConn{files: map[string]string{}}
+	d := NewFtpDeployer(uploadSettings{}, false)
+	d.dial = func() (ftpConn, error) { return conn, nil }
+
+	dp := NewDeployment(targetDir, "/htdocs", d)
+	dp.out = ioutil.Discard
+	if err := dp.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conn.files) != 1 || conn.files["/htdocs/blog/index.html"] != "blog" {
+		t.Error("Expected only /htdocs/blog/index.html to be uploaded, but got", conn.files)
+	}
+	if strings.Join(conn.dirs, ",") != "/htdocs,/htdocs/blog" {
+		t.Error("Expected parent dirs to be created, but got", conn.dirs)
+	}
+}
+
+func TestDeploymentDryRun(t *testing.T) {
+	targetDir := givenBuild(t, map[string]string{"index.html": "home"})
+	defer os.RemoveAll(filepath.Dir(targetDir))
+
+	out := new(bytes.Buffer)
+	dp := NewDeployment(targetDir, "/htdocs", nil)
+	dp.dryRun = true
+	dp.out = out
+	if err := dp.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "(dry run) [1/1] upload index.html\n"
+	if out.String() != expected {
+		t.Error("Expected", expected, ", but got", out.String())
+	}
+	exists, _ := fs.PathExists(deployedManifestPath(targetDir))
+	if exists {
+		t.Error("Expected dry run not to record a deployment")
+	}
+}
+
+func TestNewDeployerUnknownProtocol(t *testing.T) {
+	_, err := NewDeployer(uploadSettings{Protocol: "gopher"})
+	if err == nil {
+		t.Error("Expected error for unknown protocol")
+	}
+}
+
+func TestDeploymentRefusesDraftBuilds(t *testing.T) {
+	targetDir := givenBuild(t, map[string]string{"index.html": "home"})
+	defer os.RemoveAll(filepath.Dir(targetDir))
+	built, _ := ReadBuildManifest(targetDir)
+	built.Drafts = true
+	built.Write()
+
+	dp := NewDeployment(targetDir, "/htdocs", nil)
+	dp.out = ioutil.Discard
+	if err := dp.Run(); err == nil {
+		t.Error("Expected a build including drafts not to be deployed")
+	}
+}
+
+// Uploads the files until the given one, then fails
+type failingDeployer struct {
+	failAt   string
+	uploaded []string
+}
+
+func (f *failingDeployer) Connect() error { return nil }
+func (f *failingDeployer) Close() error   { return nil }
+
+func (f *failingDeployer) Upload(localPath, remotePath string) error {
+	if path.Base(remotePath) == f.failAt {
+		return errors.New("connection lost")
+	}
+	f.uploaded = append(f.uploaded, remotePath)
+	return nil
+}
+
+func (f *failingDeployer) Remove(remotePath string) error { return nil }
+
+func TestDeploymentRecordsPartialUploads(t *testing.T) {
+	targetDir := givenBuild(t, map[string]string{
+		"a.html": "a",
+		"b.html": "b"})
+	defer os.RemoveAll(filepath.Dir(targetDir))
+
+	d := &failingDeployer{failAt: "b.html"}
+	dp := NewDeployment(targetDir, "/htdocs", d)
+	dp.out = ioutil.Discard
+	if err := dp.Run(); err == nil {
+		t.Error("Expected the failed upload to be reported")
+	}
+
+	deployed, _ := ReadDeployedManifest(targetDir)
+	if len(deployed.Files) != 1 || deployed.Files["a.html"] != contentHash("a") {
+		t.Error("Expected only a.html to be recorded, but got", deployed.Files)
+	}
+
+	d.failAt = ""
+	d.uploaded = nil
+	if err := dp.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(d.uploaded, ",") != "/htdocs/b.html" {
+		t.Error("Expected only b.html to be uploaded again, but got", d.uploaded)
+	}
+}
+
+// Uploads all files in one call, like rsync
+type failingBatchDeployer struct {
+	failingDeployer
+}
+
+func (f *failingBatchDeployer) UploadAll(localDir, remoteDir string, rels []string) ([]string, error) {
+	uploaded := []string{}
+	for _, rel := range rels {
+		if err := f.Upload(filepath.Join(localDir, rel), path.Join(remoteDir, rel)); err != nil {
+			return uploaded, err
+		}
+		uploaded = append(uploaded, rel)
+	}
+	return uploaded, nil
+}
+
+func TestBatchDeploymentRecordsPartialUploads(t *testing.T) {
+	targetDir := givenBuild(t, map[string]string{
+		"a.html": "a",
+		"b.html": "b"})
+	defer os.RemoveAll(filepath.Dir(targetDir))
+
+	d := &failingBatchDeployer{failingDeployer{failAt: "b.html"}}
+	dp := NewDeployment(targetDir, "/htdocs", d)
+	dp.out = ioutil.Discard
+	if err := dp.Run(); err == nil {
+		t.Error("Expected the failed upload to be reported")
+	}
+
+	deployed, _ := ReadDeployedManifest(targetDir)
+	if len(deployed.Files) != 1 || deployed.Files["a.html"] != contentHash("a") {
+		t.Error("Expected only a.html to be recorded, but got", deployed.Files)
+	}
+}
+
+func TestRsyncDeployerQuotesPaths(t *testing.T) {
+	r := NewRsyncDeployer(uploadSettings{
+		Host:    "example.com",
+		User:    "me",
+		KeyFile: "/home/me/my keys/id_rsa"})
+
+	expected := `'ssh' '-o' 'BatchMode=yes' '-i' '/home/me/my keys/id_rsa'`
+	if r.shell() != expected {
+		t.Error("Expected", expected, ", but got", r.shell())
+	}
+	if q := shellQuote("it's; rm -rf ~"); q != `'it'\''s; rm -rf ~'` {
+		t.Error("Expected the quote to be escaped, but got", q)
+	}
+
+	args := r.uploadAllArgs("/tmp/files", "deploy", "/htdocs")
+	expectedArgs := []string{"--mkpath", "--protect-args", "--times", "--out-format=%n",
+		"--files-from=/tmp/files", "-e", expected, "deploy/", "me@example.com:/htdocs/"}
+	if strings.Join(args, "|") != strings.Join(expectedArgs, "|") {
+		t.Error("Expected", expectedArgs, ", but got", args)
+	}
+
+	transferred := transferredFiles("blog/\nblog/index.html\nindex.html\n", []string{"index.html", "blog/index.html", "feed.xml"})
+	if strings.Join(transferred, ",") != "index.html,blog/index.html" {
+		t.Error("Expected the reported files, but got", transferred)
+	}
+}
+
+func TestRsyncVersionIsChecked(t *testing.T) {
+	cases := map[string]bool{
+		"rsync  version 3.2.3  protocol version 31\n":  true,
+		"rsync  version v3.2.7  protocol version 31\n": true,
+		"rsync  version 4.0.0  protocol version 32\n":  true,
+		"rsync  version 3.1.3  protocol version 31\n":  false,
+		"rsync  version 2.6.9  protocol version 29\n":  false,
+		"openrsync: protocol version 29\n":             false}
+	for output, ok := range cases {
+		if err := checkRsyncVersion(output); (err == nil) != ok {
+			t.Error("Expected the check of", output, "to pass:", ok, ", but got", err)
+		}
+	}
+}
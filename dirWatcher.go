@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Creates a new dirWatcher, which polls the given
+// directories for changes of the page json and
+// markdown files within them
+func NewDirWatcher(interval time.Duration, dirs ...string) *dirWatcher {
+	w := new(dirWatcher)
+	w.interval = interval
+	w.dirs = dirs
+	w.extensions = []string{".json", ".md"}
+	w.stamps = w.scan()
+	return w
+}
+
+// A file is considered changed if its modification
+// time or its size differs from the last scan
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+type dirWatcher struct {
+	dirs       []string
+	extensions []string
+
+	// dirs watched with all files below them,
+	// which might not exist yet
+	trees []string
+
+	interval time.Duration
+	stamps   map[string]fileStamp
+}
+
+// Additionally watches all files below the given dirs,
+// like the templates of a site
+func (w *dirWatcher) addTrees(dirs ...string) {
+	w.trees = append(w.trees, dirs...)
+	w.stamps = w.scan()
+}
+
+// Blocks and calls the given function each time
+// a change within the watched directories is detected
+func (w *dirWatcher) Watch(onChange func()) {
+	for {
+		time.Sleep(w.interval)
+		if w.changed() {
+			onChange()
+		}
+	}
+}
+
+// Compares the current state of the watched
+// directories with the last scan
+func (w *dirWatcher) changed() bool {
+	current := w.scan()
+	defer func() { w.stamps = current }()
+
+	if len(current) != len(w.stamps) {
+		return true
+	}
+	for file, stamp := range current {
+		last, ok := w.stamps[file]
+		if !ok || !last.modTime.Equal(stamp.modTime) || last.size != stamp.size {
+			log.Debug("dirWatcher.changed() - changed file: " + file)
+			return true
+		}
+	}
+	return false
+}
+
+func (w *dirWatcher) scan() map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, dir := range w.dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			log.Error("dirWatcher.scan() - ", err)
+			continue
+		}
+		for _, info := range infos {
+			if info.IsDir() || !w.watches(info.Name()) {
+				continue
+			}
+			stamps[filepath.Join(dir, info.Name())] = fileStamp{
+				info.ModTime(),
+				info.Size()}
+		}
+	}
+	for _, tree := range w.trees {
+		w.scanTree(tree, stamps)
+	}
+	return stamps
+}
+
+func (w *dirWatcher) scanTree(tree string, stamps map[string]fileStamp) {
+	filepath.Walk(tree, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") && p != tree {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			stamps[p] = fileStamp{info.ModTime(), info.Size()}
+		}
+		return nil
+	})
+}
+
+func (w *dirWatcher) watches(filename string) bool {
+	ext := filepath.Ext(filename)
+	for _, e := range w.extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
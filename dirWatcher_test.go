@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestDirWatcherChanged(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "watcher")
+	defer os.RemoveAll(dir)
+
+	w := NewDirWatcher(time.Millisecond, dir)
+	if w.changed() {
+		t.Error("Expected no change in untouched dir")
+	}
+
+	ioutil.WriteFile(path.Join(dir, "doc00000.json"), []byte("{}"), 0644)
+	if !w.changed() {
+		t.Error("Expected new json file to be detected")
+	}
+
+	ioutil.WriteFile(path.Join(dir, "post.md"), []byte("# Post"), 0644)
+	if !w.changed() {
+		t.Error("Expected new markdown file to be detected")
+	}
+
+	ioutil.WriteFile(path.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+	if w.changed() {
+		t.Error("Expected files without json or md extension to be ignored")
+	}
+}
+
+func TestDirWatcherWatchesTrees(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "watcher")
+	defer os.RemoveAll(dir)
+	tree := path.Join(dir, "templates")
+
+	w := NewDirWatcher(time.Millisecond)
+	w.addTrees(tree)
+	if w.changed() {
+		t.Error("Expected no change for a missing dir")
+	}
+
+	os.MkdirAll(path.Join(tree, "partials"), 0755)
+	ioutil.WriteFile(path.Join(tree, "partials", "post.html"), []byte("<p>"), 0644)
+	if !w.changed() {
+		t.Error("Expected new file below the tree to be detected")
+	}
+
+	ioutil.WriteFile(path.Join(tree, ".post.html.swp"), []byte("swap"), 0644)
+	if w.changed() {
+		t.Error("Expected hidden files to be ignored")
+	}
+}
@@ -2,12 +2,19 @@ package main
 
 import "github.com/ingmardrewing/staticIntf"
 
-// element bundle constructor
-func newElementBundle() *elementBundle {
-	return new(elementBundle)
+// element bundle constructor, bundles hold up to size
+// elements, defaultPageSize if size isn't positive
+func newElementBundle(size int) *elementBundle {
+	b := new(elementBundle)
+	b.size = size
+	if b.size <= 0 {
+		b.size = defaultPageSize
+	}
+	return b
 }
 
 type elementBundle struct {
+	size     int
 	elements []staticIntf.Page
 }
 
@@ -16,7 +23,7 @@ func (l *elementBundle) addElement(e staticIntf.Page) {
 }
 
 func (l *elementBundle) full() bool {
-	return len(l.elements) >= 10
+	return len(l.elements) >= l.size
 }
 
 func (l *elementBundle) getElements() []staticIntf.Page {
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"html"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	atomFilename     = "atom.xml"
+	jsonFeedFilename = "feed.json"
+	jsonFeedVersion  = "https://jsonfeed.org/version/1.1"
+
+	// length of the summaries derived from the
+	// content of pages without excerpt
+	feedSummaryLength = 300
+)
+
+var (
+	htmlTagRegex    = regexp.MustCompile(`<[^>]*>`)
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// A feed of the newest pages of a source,
+// or of those pages having one tag
+type feed struct {
+	title   string
+	homeUrl string
+	dir     string
+	domain  string
+	author  string
+	updated time.Time
+	items   []*feedItem
+}
+
+// One page within a feed
+type feedItem struct {
+	url       string
+	title     string
+	published time.Time
+	content   string
+	summary   string
+	tags      []string
+	image     string
+}
+
+// Returns the absolute url of a file of the feed
+func (f *feed) fileUrl(filename string) string {
+	return "https://" + f.domain + "/" + path.Join(f.dir, filename)
+}
+
+// Renders the feed as Atom 1.0 document
+func (f *feed) atom() ([]byte, error) {
+	doc := atomFeed{
+		ID:      f.homeUrl,
+		Title:   atomText{Body: f.title},
+		Updated: f.updated.Format(time.RFC3339),
+		Author:  &atomPerson{Name: f.author},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.fileUrl(atomFilename)},
+			{Rel: "alternate", Type: "text/html", Href: f.homeUrl}}}
+
+	for _, item := range f.items {
+		entry := atomEntry{
+			ID:        item.url,
+			Title:     atomText{Type: "html", Body: item.title},
+			Updated:   item.published.Format(time.RFC3339),
+			Published: item.published.Format(time.RFC3339),
+			Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: item.url}}}
+		if item.image != "" {
+			entry.Links = append(entry.Links, atomLink{
+				Rel:  "enclosure",
+				Type: mime.TypeByExtension(path.Ext(item.image)),
+				Href: item.image})
+		}
+		for _, tag := range item.tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		if item.summary != "" {
+			entry.Summary = &atomText{Type: "text", Body: item.summary}
+		}
+		if item.content != "" {
+			entry.Content = &atomText{Type: "html", Body: item.content}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// Renders the feed as JSON Feed 1.1 document
+func (f *feed) jsonFeed() ([]byte, error) {
+	doc := jsonFeedDoc{
+		Version:     jsonFeedVersion,
+		Title:       f.title,
+		HomePageUrl: f.homeUrl,
+		FeedUrl:     f.fileUrl(jsonFeedFilename),
+		Authors:     []jsonFeedAuthor{{Name: f.author}},
+		Items:       []jsonFeedItem{}}
+
+	for _, item := range f.items {
+		ji := jsonFeedItem{
+			ID:            item.url,
+			Url:           item.url,
+			Title:         item.title,
+			ContentHtml:   item.content,
+			Summary:       item.summary,
+			Image:         item.image,
+			DatePublished: item.published.Format(time.RFC3339),
+			Tags:          item.tags}
+		if ji.ContentHtml == "" {
+			ji.ContentText = item.summary
+		}
+		doc.Items = append(doc.Items, ji)
+	}
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	err := enc.Encode(doc)
+	return buf.Bytes(), err
+}
+
+// Turns the html of a page into plain text
+func plainText(content string) string {
+	text := html.UnescapeString(htmlTagRegex.ReplaceAllString(content, " "))
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(text, " "))
+}
+
+// Shortens the text to at most max characters,
+// cutting it at a word boundary
+func truncateText(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)[:max]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + " …"
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   atomText    `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type jsonFeedDoc struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageUrl string           `json:"home_page_url"`
+	FeedUrl     string           `json:"feed_url"`
+	Authors     []jsonFeedAuthor `json:"authors"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	Url           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHtml   string   `json:"content_html,omitempty"`
+	ContentText   string   `json:"content_text,omitempty"`
+	Summary       string   `json:"summary,omitempty"`
+	Image         string   `json:"image,omitempty"`
+	DatePublished string   `json:"date_published"`
+	Tags          []string `json:"tags,omitempty"`
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ingmardrewing/staticIntf"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	feedFull    = "full"
+	feedExcerpt = "excerpt"
+
+	defaultFeedLimit = 20
+)
+
+// Creates a feedBuilder for the source located below
+// the given subDir of the site, titled by headline
+func NewFeedBuilder(
+	site staticIntf.Site,
+	subDir, headline, author string,
+	settings feedSettings) *feedBuilder {
+
+	f := new(feedBuilder)
+	f.site = site
+	f.subDir = subDir
+	f.headline = headline
+	f.author = author
+	f.settings = settings
+	if f.settings.Content == "" {
+		f.settings.Content = feedFull
+	}
+	if f.settings.Limit == 0 {
+		f.settings.Limit = defaultFeedLimit
+	}
+	f.entries = map[string]*feedEntry{}
+	return f
+}
+
+// The feedBuilder creates the feeds of a source, one
+// of all pages and one per tag. Tags, excerpts, dates
+// and images of the pages are collected while the page
+// documents are loaded.
+type feedBuilder struct {
+	site     staticIntf.Site
+	subDir   string
+	headline string
+	author   string
+	settings feedSettings
+
+	// link the tag feeds to the tag archives of a blog
+	tagArchives bool
+
+	entries map[string]*feedEntry
+}
+
+// The data of a page document used by the feeds
+type feedEntry struct {
+	tags       []string
+	excerpt    string
+	createDate string
+	images     []imageUrls
+}
+
+// Records the data of a page needed by
+// the feeds, to be used as pageTransform
+func (f *feedBuilder) collectPost(doc *pageDoc) (bool, error) {
+	f.entries[path.Join(doc.PathFromDocRoot, doc.Filename)] = &feedEntry{
+		tags:       append([]string{}, doc.Tags...),
+		excerpt:    doc.Excerpt,
+		createDate: doc.CreateDate,
+		images:     append([]imageUrls{}, doc.ImagesUrls...)}
+	return false, nil
+}
+
+// Creates the feed of all pages of the given container
+// and one feed per tag, tags sorted. Sources without
+// dated pages get no feeds.
+func (f *feedBuilder) Feeds(posts staticIntf.PagesContainer) []*feed {
+	if f.site == nil {
+		return nil
+	}
+
+	items := []*feedItem{}
+	tagNames := map[string]string{}
+	for _, p := range reversePages(posts.Pages()) {
+		item := f.item(p)
+		if item == nil {
+			continue
+		}
+		items = append(items, item)
+		// tags are named as on their oldest
+		// page, like the tag archives do
+		for _, tag := range item.tags {
+			if slug := slugify(tag); slug != "" {
+				tagNames[slug] = tag
+			}
+		}
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	feeds := []*feed{f.feed(f.headline, f.homeUrl(), f.subDir, items)}
+	slugs := []string{}
+	for slug := range tagNames {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	for _, slug := range slugs {
+		tagged := []*feedItem{}
+		for _, item := range items {
+			for _, tag := range item.tags {
+				if slugify(tag) == slug {
+					tagged = append(tagged, item)
+					break
+				}
+			}
+		}
+		dir := path.Join(f.subDir, "tags", slug)
+		homeUrl := f.homeUrl()
+		if f.tagArchives {
+			homeUrl = "https://" + f.site.Domain() + "/" + dir + "/"
+		}
+		title := strings.TrimSpace(f.headline + " – Tag: " + tagNames[slug])
+		feeds = append(feeds, f.feed(title, homeUrl, dir, tagged))
+	}
+	return feeds
+}
+
+func (f *feedBuilder) homeUrl() string {
+	return "https://" + path.Join(f.site.Domain(), f.subDir) + "/"
+}
+
+// Creates a feed of the newest of the given items,
+// which are ordered from the newest to the oldest
+func (f *feedBuilder) feed(title, homeUrl, dir string, items []*feedItem) *feed {
+	if title == "" {
+		title = f.site.Domain()
+	}
+	author := f.author
+	if author == "" {
+		author = f.site.Domain()
+	}
+	if f.settings.Limit > 0 && len(items) > f.settings.Limit {
+		items = items[:f.settings.Limit]
+	}
+
+	fd := &feed{
+		title:   title,
+		homeUrl: homeUrl,
+		dir:     dir,
+		domain:  f.site.Domain(),
+		author:  author,
+		items:   items}
+	for _, item := range items {
+		if item.published.After(fd.updated) {
+			fd.updated = item.published
+		}
+	}
+	return fd
+}
+
+// Creates the feed item of a page, or nil
+// if the page has no date to be listed by
+func (f *feedBuilder) item(p staticIntf.Page) *feedItem {
+	entry := f.entries[path.Join(p.PathFromDocRoot(), p.HtmlFilename())]
+	if entry == nil {
+		entry = new(feedEntry)
+	}
+
+	published, ok := pageDate(entry.createDate, p.PublishedTime())
+	if !ok {
+		log.Warnf("%s is left out of the feeds, its date can't be parsed", p.Url())
+		return nil
+	}
+
+	summary := entry.excerpt
+	if summary == "" {
+		summary = p.Description()
+	}
+	if summary == "" {
+		summary = truncateText(plainText(p.Content()), feedSummaryLength)
+	} else {
+		summary = plainText(summary)
+	}
+
+	item := &feedItem{
+		url:       p.Url(),
+		title:     p.Title(),
+		published: published,
+		summary:   summary,
+		tags:      entry.tags,
+		image:     f.imageUrl(entry.images, p.ImageUrl())}
+	if f.settings.Content == feedFull {
+		item.content = p.Content()
+	}
+	return item
+}
+
+// Parses the first of the given dates which
+// is in one of the known formats
+func pageDate(dates ...string) (time.Time, bool) {
+	for _, date := range dates {
+		if date == "" {
+			continue
+		}
+		if t, err := parsePublishDate(date); err == nil {
+			return t, true
+		}
+		if t, err := time.Parse(time.RFC1123Z, date); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Returns the absolute url of the largest variant
+// of the first image of a page, or of the fallback
+func (f *feedBuilder) imageUrl(images []imageUrls, fallback string) string {
+	url := fallback
+	if len(images) > 0 {
+		img := images[0]
+		for _, u := range []string{img.MaxResolution, img.W800, img.W390, img.W190} {
+			if u != "" {
+				url = u
+				break
+			}
+		}
+	}
+	return f.absoluteUrl(url)
+}
+
+// Turns urls relative to the doc root of the site into
+// absolute ones, as feed readers don't resolve them
+func (f *feedBuilder) absoluteUrl(url string) string {
+	switch {
+	case url == "", strings.Contains(url, "://"):
+		return url
+	case strings.HasPrefix(url, "//"):
+		return "https:" + url
+	}
+	return "https://" + f.site.Domain() + "/" + strings.TrimPrefix(url, "/")
+}
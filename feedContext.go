@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticIntf"
+	log "github.com/sirupsen/logrus"
+)
+
+// Sources offering feeds of their pages
+type feedSource interface {
+	Feeds() []*feed
+}
+
+// Creates the context rendering the given
+// feeds into the given target dir
+func NewFeedContext(targetDir string, feeds []*feed) *feedContext {
+	c := new(feedContext)
+	c.targetDir = targetDir
+	c.feeds = feeds
+	return c
+}
+
+// The feedContext renders each feed as Atom
+// and as JSON Feed document
+type feedContext struct {
+	targetDir string
+	feeds     []*feed
+}
+
+// Feeds don't need any css
+func (c *feedContext) GetComponents() []staticIntf.Component {
+	return []staticIntf.Component{}
+}
+
+func (c *feedContext) RenderPages() []fs.FileContainer {
+	fcs := []fs.FileContainer{}
+	for _, f := range c.feeds {
+		renderers := map[string]func() ([]byte, error){
+			atomFilename:     f.atom,
+			jsonFeedFilename: f.jsonFeed}
+		for _, filename := range []string{atomFilename, jsonFeedFilename} {
+			data, err := renderers[filename]()
+			if err != nil {
+				log.Errorf("rendering %s of %s: %v", filename, f.homeUrl, err)
+				continue
+			}
+			fc := fs.NewFileContainer()
+			fc.SetData(data)
+			fc.SetPath(filepath.Join(c.targetDir, f.dir))
+			fc.SetFilename(filename)
+			fcs = append(fcs, fc)
+		}
+	}
+	return fcs
+}
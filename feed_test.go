@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ingmardrewing/staticIntf"
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+// Builds the site of the given src entries, which may refer
+// to the dir of the tagged posts as $posts, and returns its
+// feeds by path
+func givenFeeds(t *testing.T, src string) map[string][]byte {
+	s := givenRenderedSite(t, func(posts string) string {
+		return `[{
+			"domain": "drewing.de",
+			"defaultMeta": {"author": "Ingmar Drewing"},
+			"deploy": {"targetDir": "deploy"},
+			"src": [` + strings.Replace(src, "$posts", strconv.Quote(posts), -1) + `]
+		}]`
+	})
+	files := map[string][]byte{}
+	for name, data := range renderedFiles(s, func(ctx staticIntf.Context) bool {
+		_, ok := ctx.(*feedContext)
+		return ok
+	}) {
+		files[name] = []byte(data)
+	}
+	return files
+}
+
+// The elements of an Atom document checked against RFC 4287
+type atomCheck struct {
+	XMLName xml.Name `xml:"feed"`
+	IDs     []string `xml:"id"`
+	Titles  []string `xml:"title"`
+	Updated []string `xml:"updated"`
+	Authors []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Links   []atomLink `xml:"link"`
+	Entries []struct {
+		IDs       []string   `xml:"id"`
+		Titles    []string   `xml:"title"`
+		Updated   []string   `xml:"updated"`
+		Published []string   `xml:"published"`
+		Authors   []struct{} `xml:"author"`
+		Links     []atomLink `xml:"link"`
+		Summary   []atomText `xml:"summary"`
+		Content   []atomText `xml:"content"`
+	} `xml:"entry"`
+}
+
+func isAbsoluteUrl(url string) bool {
+	return strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")
+}
+
+func isRFC3339(date string) bool {
+	_, err := time.Parse(time.RFC3339, date)
+	return err == nil
+}
+
+// Checks the constraints of RFC 4287 the generated feeds
+// could violate, reporting them with the name of the file
+func validateAtom(t *testing.T, name string, data []byte) *atomCheck {
+	doc := new(atomCheck)
+	if err := xml.Unmarshal(data, doc); err != nil {
+		t.Error("Expected", name, "to be well-formed xml, but got", err)
+		return doc
+	}
+	if doc.XMLName.Space != "http://www.w3.org/2005/Atom" {
+		t.Error("Expected the Atom namespace in", name, ", but got", doc.XMLName.Space)
+	}
+	if len(doc.IDs) != 1 || !isAbsoluteUrl(doc.IDs[0]) {
+		t.Error("Expected one absolute atom:id in", name, ", but got", doc.IDs)
+	}
+	if len(doc.Titles) != 1 || doc.Titles[0] == "" {
+		t.Error("Expected one atom:title in", name, ", but got", doc.Titles)
+	}
+	if len(doc.Updated) != 1 || !isRFC3339(doc.Updated[0]) {
+		t.Error("Expected one RFC 3339 atom:updated in", name, ", but got", doc.Updated)
+	}
+	self := 0
+	for _, l := range doc.Links {
+		if !isAbsoluteUrl(l.Href) {
+			t.Error("Expected absolute links in", name, ", but got", l.Href)
+		}
+		if l.Rel == "self" {
+			self++
+		}
+	}
+	if self != 1 {
+		t.Error("Expected one self link in", name, ", but got", self)
+	}
+
+	ids := map[string]bool{}
+	for _, e := range doc.Entries {
+		if len(doc.Authors) == 0 && len(e.Authors) == 0 {
+			t.Error("Expected an atom:author for each entry of", name)
+		}
+		if len(e.IDs) != 1 || !isAbsoluteUrl(e.IDs[0]) || ids[e.IDs[0]] {
+			t.Error("Expected one unique absolute atom:id per entry in", name, ", but got", e.IDs)
+		} else {
+			ids[e.IDs[0]] = true
+		}
+		if len(e.Titles) != 1 {
+			t.Error("Expected one atom:title per entry in", name, ", but got", e.Titles)
+		}
+		if len(e.Updated) != 1 || !isRFC3339(e.Updated[0]) {
+			t.Error("Expected one RFC 3339 atom:updated per entry in", name, ", but got", e.Updated)
+		}
+		if len(e.Published) > 1 || (len(e.Published) == 1 && !isRFC3339(e.Published[0])) {
+			t.Error("Expected at most one RFC 3339 atom:published per entry in", name, ", but got", e.Published)
+		}
+		if len(e.Summary) > 1 || len(e.Content) > 1 {
+			t.Error("Expected at most one atom:summary and atom:content per entry in", name)
+		}
+		alternate := false
+		for _, l := range e.Links {
+			if !isAbsoluteUrl(l.Href) {
+				t.Error("Expected absolute links in", name, ", but got", l.Href)
+			}
+			alternate = alternate || l.Rel == "alternate"
+		}
+		if len(e.Content) == 0 && !alternate {
+			t.Error("Expected atom:content or an alternate link per entry in", name)
+		}
+	}
+	return doc
+}
+
+// Checks the requirements of JSON Feed 1.1 the generated
+// feeds could violate, reporting them with the name of the file
+func validateJsonFeed(t *testing.T, name string, data []byte) map[string]interface{} {
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Error("Expected", name, "to be valid json, but got", err)
+		return doc
+	}
+	if doc["version"] != "https://jsonfeed.org/version/1.1" {
+		t.Error("Expected version 1.1 in", name, ", but got", doc["version"])
+	}
+	if title, _ := doc["title"].(string); title == "" {
+		t.Error("Expected a title in", name)
+	}
+	for _, key := range []string{"home_page_url", "feed_url"} {
+		if url, _ := doc[key].(string); !isAbsoluteUrl(url) {
+			t.Error("Expected an absolute", key, "in", name, ", but got", doc[key])
+		}
+	}
+	if _, ok := doc["author"]; ok {
+		t.Error("Expected authors instead of the deprecated author in", name)
+	}
+	authors, _ := doc["authors"].([]interface{})
+	for _, a := range authors {
+		if author, _ := a.(map[string]interface{}); author["name"] == nil && author["url"] == nil && author["avatar"] == nil {
+			t.Error("Expected name, url or avatar for each author in", name)
+		}
+	}
+
+	items, ok := doc["items"].([]interface{})
+	if !ok {
+		t.Error("Expected an items array in", name, ", but got", doc["items"])
+	}
+	ids := map[string]bool{}
+	for _, i := range items {
+		item, _ := i.(map[string]interface{})
+		id, _ := item["id"].(string)
+		if id == "" || ids[id] {
+			t.Error("Expected a unique id per item in", name, ", but got", item["id"])
+		}
+		ids[id] = true
+		if item["content_html"] == nil && item["content_text"] == nil {
+			t.Error("Expected content_html or content_text per item in", name)
+		}
+		for _, key := range []string{"url", "image"} {
+			if url, ok := item[key].(string); ok && !isAbsoluteUrl(url) {
+				t.Error("Expected an absolute", key, "per item in", name, ", but got", url)
+			}
+		}
+		if date, ok := item["date_published"].(string); ok && !isRFC3339(date) {
+			t.Error("Expected an RFC 3339 date_published in", name, ", but got", date)
+		}
+		if len(authors) == 0 && item["authors"] == nil {
+			t.Error("Expected authors for the feed or each item in", name)
+		}
+	}
+	return doc
+}
+
+func TestFeedsOfTestResourcesAreValid(t *testing.T) {
+	files := givenFeeds(t, `
+		{"dir": "`+absPath("testResources/src/posts/")+`", "type": "blog", "subDir": "blog", "headline": "Blog"},
+		{"dir": "`+absPath("testResources/src/narrative/")+`", "type": "narrative", "subDir": "devabo.de"}`)
+
+	for _, name := range []string{"deploy/blog/atom.xml", "deploy/blog/feed.json"} {
+		if _, ok := files[name]; !ok {
+			t.Error("Expected the feed", name, ", but got", len(files), "feed files")
+		}
+	}
+	for name, data := range files {
+		switch filepath.Ext(name) {
+		case ".xml":
+			validateAtom(t, name, data)
+		case ".json":
+			validateJsonFeed(t, name, data)
+		}
+	}
+
+	atom := validateAtom(t, "atom.xml", files["deploy/blog/atom.xml"])
+	if len(atom.Entries) != defaultFeedLimit {
+		t.Error("Expected", defaultFeedLimit, "entries, but got", len(atom.Entries))
+	}
+}
+
+func TestFeedsPerTag(t *testing.T) {
+	files := givenFeeds(t, `{"dir": $posts, "type": "blog", "subDir": "blog", "headline": "Blog"}`)
+
+	cases := map[string]int{
+		"deploy/blog/atom.xml":              3,
+		"deploy/blog/tags/go/atom.xml":      2,
+		"deploy/blog/tags/static/atom.xml":  1,
+		"deploy/blog/tags/drawing/atom.xml": 1}
+	for name, expected := range cases {
+		atom := validateAtom(t, name, files[name])
+		if len(atom.Entries) != expected {
+			t.Error("Expected", expected, "entries in", name, ", but got", len(atom.Entries))
+		}
+	}
+
+	jf := validateJsonFeed(t, "tags/go/feed.json", files["deploy/blog/tags/go/feed.json"])
+	if jf["home_page_url"] != "https://drewing.de/blog/tags/go/" {
+		t.Error("Expected the tag archive as home page, but got", jf["home_page_url"])
+	}
+	if jf["title"] != "Blog – Tag: go" {
+		t.Error("Expected the tag in the title, but got", jf["title"])
+	}
+}
+
+func TestFeedContentAndImages(t *testing.T) {
+	full := givenFeeds(t, `{"dir": $posts, "type": "blog", "subDir": "blog", "headline": "Blog"}`)
+	jf := validateJsonFeed(t, "feed.json", full["deploy/blog/feed.json"])
+	items := jf["items"].([]interface{})
+	newest := items[0].(map[string]interface{})
+	oldest := items[2].(map[string]interface{})
+	if newest["title"] != "Post & 2" {
+		t.Error("Expected the newest post first, but got", newest["title"])
+	}
+	if html, _ := newest["content_html"].(string); !strings.Contains(html, "<em>text</em>") {
+		t.Error("Expected the full content, but got", newest["content_html"])
+	}
+	if newest["image"] != "https://cdn.drewing.de/third.jpg" {
+		t.Error("Expected", "https://cdn.drewing.de/third.jpg", ", but got", newest["image"])
+	}
+	if oldest["image"] != "https://drewing.de/img/first.png" {
+		t.Error("Expected", "https://drewing.de/img/first.png", ", but got", oldest["image"])
+	}
+
+	excerpts := givenFeeds(t, `{"dir": $posts, "type": "blog", "subDir": "blog",
+		"feed": {"content": "excerpt", "limit": 2}}`)
+	atom := validateAtom(t, "atom.xml", excerpts["deploy/blog/atom.xml"])
+	if len(atom.Entries) != 2 {
+		t.Error("Expected", 2, "entries, but got", len(atom.Entries))
+	}
+	entry := atom.Entries[0]
+	if len(entry.Content) != 0 {
+		t.Error("Expected no content, but got", entry.Content)
+	}
+	if len(entry.Summary) != 1 || entry.Summary[0].Body != "Excerpt 2" {
+		t.Error("Expected the excerpt as summary, but got", entry.Summary)
+	}
+	jf = validateJsonFeed(t, "feed.json", excerpts["deploy/blog/feed.json"])
+	item := jf["items"].([]interface{})[0].(map[string]interface{})
+	if item["content_text"] != "Excerpt 2" || item["content_html"] != nil {
+		t.Error("Expected the excerpt as content_text, but got", item)
+	}
+}
+
+func absPath(p string) string {
+	abs, _ := filepath.Abs(p)
+	return abs
+}
+
+func TestFeedsOfRootSourcesCollide(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "feeds")
+	defer os.RemoveAll(dir)
+	blog := givenTaggedPosts(filepath.Join(dir, "blog"))
+	narrative := givenTaggedPosts(filepath.Join(dir, "narrative"))
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`[{
+		"domain": "drewing.de",
+		"deploy": {"targetDir": "deploy"},
+		"src": [
+			{"dir": `+strconv.Quote(blog)+`, "type": "blog", "subDir": "", "headline": "Blog"},
+			{"dir": `+strconv.Quote(narrative)+`, "type": "narrative", "subDir": ""}
+		]
+	}]`), 0644)
+	configs := staticPersistence.ReadConfig(dir, "config.json")
+
+	s := NewSitesController(configs).renderSite(0)
+	err := s.errs.orNil()
+	if err == nil || !strings.Contains(err.Error(), `src[1] narrative::`+narrative+`: its feeds in "/" would overwrite those of blog::`+blog) {
+		t.Error("Expected the colliding feeds to be reported, but got", err)
+	}
+	feeds := 0
+	for name := range renderedFiles(s, func(ctx staticIntf.Context) bool {
+		_, ok := ctx.(*feedContext)
+		return ok
+	}) {
+		if name == "deploy/atom.xml" {
+			feeds++
+		}
+	}
+	if feeds != 1 {
+		t.Error("Expected the feed of the blog only, but got", feeds)
+	}
+}
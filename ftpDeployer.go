@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+)
+
+// The subset of an ftp connection used
+// by the ftpDeployer
+type ftpConn interface {
+	MakeDir(path string) error
+	Stor(path string, r io.Reader) error
+	Delete(path string) error
+	Quit() error
+}
+
+// Creates a deployer uploading via ftp, using
+// explicit TLS if useTls is true
+func NewFtpDeployer(settings uploadSettings, useTls bool) *ftpDeployer {
+	f := new(ftpDeployer)
+	f.settings = settings
+	f.useTls = useTls
+	f.dial = f.dialFtp
+	return f
+}
+
+type ftpDeployer struct {
+	settings uploadSettings
+	useTls   bool
+	dial     func() (ftpConn, error)
+	conn     ftpConn
+	dirs     map[string]bool
+}
+
+func (f *ftpDeployer) Connect() error {
+	conn, err := f.dial()
+	if err != nil {
+		return err
+	}
+	f.conn = conn
+	f.dirs = map[string]bool{}
+	return nil
+}
+
+func (f *ftpDeployer) Upload(localPath, remotePath string) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	f.makeDirs(path.Dir(remotePath))
+	return f.conn.Stor(remotePath, src)
+}
+
+func (f *ftpDeployer) Remove(remotePath string) error {
+	return f.conn.Delete(remotePath)
+}
+
+func (f *ftpDeployer) Close() error {
+	if f.conn == nil {
+		return nil
+	}
+	return f.conn.Quit()
+}
+
+// Creates the given directory and all its parents.
+// ftp has no way to tell an existing directory from
+// a failure, so errors are left to the following Stor.
+func (f *ftpDeployer) makeDirs(dir string) {
+	current := ""
+	if strings.HasPrefix(dir, "/") {
+		current = "/"
+	}
+	for _, part := range strings.Split(dir, "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		current = path.Join(current, part)
+		if !f.dirs[current] {
+			f.conn.MakeDir(current)
+			f.dirs[current] = true
+		}
+	}
+}
+
+func (f *ftpDeployer) dialFtp() (ftpConn, error) {
+	port := f.settings.Port
+	if port == 0 {
+		port = 21
+	}
+	options := []ftp.DialOption{ftp.DialWithTimeout(30 * time.Second)}
+	if f.useTls {
+		options = append(options, ftp.DialWithExplicitTLS(
+			&tls.Config{ServerName: f.settings.Host}))
+	}
+
+	addr := net.JoinHostPort(f.settings.Host, strconv.Itoa(port))
+	conn, err := ftp.Dial(addr, options...)
+	if err != nil {
+		return nil, err
+	}
+	err = conn.Login(f.settings.User, os.Getenv(f.settings.PasswordEnv))
+	if err != nil {
+		conn.Quit()
+		return nil, err
+	}
+	return conn, nil
+}
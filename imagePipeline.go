@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticPersistence"
+	log "github.com/sirupsen/logrus"
+)
+
+// Creates an imagePipeline for the site of the given config
+func NewImagePipeline(config staticPersistence.Config) *imagePipeline {
+	p := new(imagePipeline)
+	p.domain = config.Domain
+	p.targetDir = config.Deploy.TargetDir
+	p.cacheDir = imageCachePath(config.Deploy.TargetDir)
+	p.written = map[string]bool{}
+	return p
+}
+
+// Returns the dir caching the generated image
+// variants, next to the deploy dir
+func imageCachePath(targetDir string) string {
+	return filepath.Clean(targetDir) + ".imagecache"
+}
+
+// The imagePipeline publishes the local images referenced
+// by the max_resolution url of a page. The missing width
+// variants are scaled from the image and cached by the
+// hash of the image, so they are only generated once.
+type imagePipeline struct {
+	domain    string
+	targetDir string
+	cacheDir  string
+
+	// the sources of a site are generated in parallel
+	// and share the pipeline, see siteCreator.addContainers
+	mu             sync.Mutex
+	fileContainers []fs.FileContainer
+	written        map[string]bool
+}
+
+// Image variant widths, and the urls of
+// a page they belong to
+func variantUrls(urls *imageUrls) []struct {
+	width int
+	url   *string
+} {
+	return []struct {
+		width int
+		url   *string
+	}{{190, &urls.W190}, {390, &urls.W390}, {800, &urls.W800}}
+}
+
+// Tells if the url references a local file
+// rather than a published one
+func isLocalImage(url string) bool {
+	if url == "" || strings.HasPrefix(url, "//") {
+		return false
+	}
+	return !strings.Contains(url, "://")
+}
+
+// Publishes the local images of the page and sets its image
+// urls to the published files, to be used as pageTransform.
+// Local paths are relative to the page json file.
+func (p *imagePipeline) processDoc(doc *pageDoc) (bool, error) {
+	changed := false
+	for i := range doc.ImagesUrls {
+		urls := &doc.ImagesUrls[i]
+		if !isLocalImage(urls.MaxResolution) {
+			continue
+		}
+		file := filepath.FromSlash(urls.MaxResolution)
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(doc.file), file)
+		}
+		if err := p.publish(doc.PathFromDocRoot, file, urls); err != nil {
+			return changed, fmt.Errorf("%s: images_urls[%d]: %v", doc.file, i, err)
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// Adds the image and its missing variants to the
+// deploy dir below the given path and sets their urls
+func (p *imagePipeline) publish(pathFromDocRoot, file string, urls *imageUrls) error {
+	if !isSupportedImage(file) {
+		return fmt.Errorf("%s is neither png nor jpeg", file)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	hash := contentHash(string(data))
+
+	filename := filepath.Base(file)
+	ext := filepath.Ext(filename)
+	name := strings.TrimSuffix(filename, ext)
+
+	var img image.Image
+	var format string
+	for _, v := range variantUrls(urls) {
+		if *v.url != "" {
+			continue
+		}
+		cacheFile := filepath.Join(p.cacheDir, fmt.Sprintf("%s-w%d%s", hash, v.width, ext))
+		if exists, _ := fs.PathExists(cacheFile); !exists {
+			if img == nil {
+				if img, format, err = decodeImageFile(file); err != nil {
+					return err
+				}
+			}
+			log.Debug("imagePipeline.publish() - scaling ", file, " to ", v.width)
+			if err := scaleIntoCache(img, v.width, format, cacheFile); err != nil {
+				return err
+			}
+		}
+		variant, err := ioutil.ReadFile(cacheFile)
+		if err != nil {
+			return err
+		}
+		*v.url = p.add(pathFromDocRoot, fmt.Sprintf("%s-w%d%s", name, v.width, ext), variant)
+	}
+	urls.MaxResolution = p.add(pathFromDocRoot, filename, data)
+	return nil
+}
+
+// Scales the image into the cache via a temporary file,
+// as several sources might scale the same image at once
+func scaleIntoCache(img image.Image, width int, format, cacheFile string) error {
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(cacheFile), ".scaling")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err := writeResizedImage(img, width, format, tmp.Name()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cacheFile)
+}
+
+// Adds a file container for the given file below the
+// path of the page and returns the url of the file
+func (p *imagePipeline) add(pathFromDocRoot, filename string, data []byte) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rel := path.Join("/", pathFromDocRoot, filename)
+	if !p.written[rel] {
+		p.written[rel] = true
+		fc := fs.NewFileContainer()
+		fc.SetDataAsString(string(data))
+		fc.SetPath(filepath.Join(p.targetDir, filepath.FromSlash(path.Dir(rel))))
+		fc.SetFilename(filename)
+		p.fileContainers = append(p.fileContainers, fc)
+	}
+	return "https://" + p.domain + rel
+}
+
+// Returns the file containers of the published
+// images, sorted by their location
+func (p *imagePipeline) FileContainers() []fs.FileContainer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fcs := append([]fs.FileContainer{}, p.fileContainers...)
+	sort.Slice(fcs, func(i, j int) bool {
+		return filepath.Join(fcs[i].GetPath(), fcs[i].GetFilename()) <
+			filepath.Join(fcs[j].GetPath(), fcs[j].GetFilename())
+	})
+	return fcs
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+func givenImagePipeline(t *testing.T) (*imagePipeline, string) {
+	dir, _ := ioutil.TempDir("", "images")
+	srcDir := filepath.Join(dir, "posts")
+	os.MkdirAll(filepath.Join(srcDir, "img"), 0755)
+
+	data, _ := ioutil.ReadFile("testResources/src/add/TestImage.png")
+	ioutil.WriteFile(filepath.Join(srcDir, "img", "TestImage.png"), data, 0644)
+
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(fmt.Sprintf(`[{
+		"domain": "drewing.de",
+		"deploy": {"targetDir": %q},
+		"src": [{"dir": %q, "type": "blog", "subDir": "blog"}]
+	}]`, filepath.Join(dir, "deploy"), srcDir)), 0644)
+	config := staticPersistence.ReadConfig(dir, "config.json")[0]
+	return NewImagePipeline(config), dir
+}
+
+func givenDocWithLocalImage(dir string) *pageDoc {
+	return &pageDoc{
+		PathFromDocRoot: "/blog/test-image/",
+		ImagesUrls: []imageUrls{{
+			W390:          "https://drewing.de/blog/existing.png",
+			MaxResolution: "img/TestImage.png"}},
+		file: filepath.Join(dir, "posts", "doc00000.json")}
+}
+
+func TestImagePipelineGeneratesMissingVariants(t *testing.T) {
+	p, dir := givenImagePipeline(t)
+	defer os.RemoveAll(dir)
+
+	doc := givenDocWithLocalImage(dir)
+	changed, err := p.processDoc(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("Expected the doc to be changed")
+	}
+
+	urls := doc.ImagesUrls[0]
+	expected := imageUrls{
+		W190:          "https://drewing.de/blog/test-image/TestImage-w190.png",
+		W390:          "https://drewing.de/blog/existing.png",
+		W800:          "https://drewing.de/blog/test-image/TestImage-w800.png",
+		MaxResolution: "https://drewing.de/blog/test-image/TestImage.png"}
+	if urls != expected {
+		t.Error("Expected", expected, ", but got", urls)
+	}
+
+	fcs := p.FileContainers()
+	expectedFiles := []string{"TestImage-w190.png", "TestImage-w800.png", "TestImage.png"}
+	if len(fcs) != len(expectedFiles) {
+		t.Fatal("Expected", len(expectedFiles), "files, but got", len(fcs))
+	}
+	for i, fc := range fcs {
+		if fc.GetFilename() != expectedFiles[i] {
+			t.Error("Expected", expectedFiles[i], ", but got", fc.GetFilename())
+		}
+		expectedPath := filepath.Join(dir, "deploy", "blog", "test-image")
+		if fc.GetPath() != expectedPath {
+			t.Error("Expected", expectedPath, ", but got", fc.GetPath())
+		}
+	}
+}
+
+func TestImagePipelineCachesVariants(t *testing.T) {
+	p, dir := givenImagePipeline(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := p.processDoc(givenDocWithLocalImage(dir)); err != nil {
+		t.Fatal(err)
+	}
+	cached, _ := filepath.Glob(filepath.Join(p.cacheDir, "*-w190.png"))
+	if len(cached) != 1 {
+		t.Fatal("Expected one cached variant, but got", cached)
+	}
+
+	// a cached variant is used instead of scaling the image again
+	ioutil.WriteFile(cached[0], []byte("cached"), 0644)
+	second, _ := givenImagePipeline(t)
+	second.cacheDir = p.cacheDir
+	if _, err := second.processDoc(givenDocWithLocalImage(dir)); err != nil {
+		t.Fatal(err)
+	}
+	fc := second.FileContainers()[0]
+	if fc.GetDataAsString() != "cached" {
+		t.Error("Expected the cached variant to be used for", fc.GetFilename())
+	}
+}
+
+func TestImagePipelineIgnoresPublishedImages(t *testing.T) {
+	p, dir := givenImagePipeline(t)
+	defer os.RemoveAll(dir)
+
+	doc := givenDocWithLocalImage(dir)
+	doc.ImagesUrls[0].MaxResolution = "https://drewing.de/blog/TestImage.png"
+	changed, err := p.processDoc(doc)
+	if err != nil || changed {
+		t.Error("Expected published images to be left as they are, but got", changed, err)
+	}
+	if doc.ImagesUrls[0].W190 != "" {
+		t.Error("Expected no variant to be generated, but got", doc.ImagesUrls[0].W190)
+	}
+}
+
+func TestImagePipelineReportsMissingImage(t *testing.T) {
+	p, dir := givenImagePipeline(t)
+	defer os.RemoveAll(dir)
+
+	doc := givenDocWithLocalImage(dir)
+	doc.ImagesUrls[0].MaxResolution = "img/Missing.png"
+	if _, err := p.processDoc(doc); err == nil {
+		t.Error("Expected an error for a missing image")
+	}
+}
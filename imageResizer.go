@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Width of the thumbnail embedded as base64 into the page json
+const thumbWidth = 150
+
+// Reads and decodes a png or jpeg image, returning
+// the image and its format
+func decodeImageFile(file string) (image.Image, string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	img, format, err := image.Decode(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %v", file, err)
+	}
+	return img, format, nil
+}
+
+// Tells if the file is an image which can be
+// decoded, judging by its extension
+func isSupportedImage(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+// Scales the image to the given width keeping its aspect
+// ratio. Images which are narrower already are returned
+// unchanged, they are never scaled up.
+func resizeToWidth(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// Encodes the image in the given format, which
+// is either png or jpeg
+func encodeImage(w io.Writer, img image.Image, format string) error {
+	if format == "jpeg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+	}
+	return png.Encode(w, img)
+}
+
+// Scales the image to the given width and writes it to the given file
+func writeResizedImage(img image.Image, width int, format, file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return encodeImage(f, resizeToWidth(img, width), format)
+}
+
+// Returns a small png version of the image, base64 encoded
+func thumbBase64(img image.Image) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, resizeToWidth(img, thumbWidth)); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ingmardrewing/fs"
+)
+
+// Matches the attributes of html elements referencing other files
+var linkAttrRegex = regexp.MustCompile(`(?i)\s(href|src|srcset)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// Creates a linkChecker for the site of the given
+// domain, rendered into the given target dir
+func NewLinkChecker(domain, targetDir string) *linkChecker {
+	c := new(linkChecker)
+	c.domain = domain
+	c.targetDir = targetDir
+	c.sources = map[string]string{}
+	return c
+}
+
+// The linkChecker finds links within the rendered
+// html files which point to files not generated by
+// the build. The page json or markdown files are
+// collected while they are loaded, to report the
+// file a broken link originates from.
+type linkChecker struct {
+	domain    string
+	targetDir string
+
+	// hosts external links may point to, external
+	// links aren't checked if there are none
+	allowedHosts []string
+
+	mu      sync.Mutex
+	sources map[string]string
+}
+
+// Records the file the page is read from,
+// to be used as pageTransform
+func (c *linkChecker) collectPost(doc *pageDoc) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources[strings.TrimPrefix(path.Join(doc.PathFromDocRoot, doc.Filename), "/")] = doc.file
+	return false, nil
+}
+
+// Checks the links of the html files among the given
+// files, which are all files of the rendered site
+func (c *linkChecker) Check(fcs []fs.FileContainer) []lintFinding {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files := map[string]fs.FileContainer{}
+	for _, fc := range fcs {
+		files[c.relPath(fc)] = fc
+	}
+	pages := []string{}
+	for rel := range files {
+		if path.Ext(rel) == ".html" {
+			pages = append(pages, rel)
+		}
+	}
+	sort.Strings(pages)
+
+	findings := []lintFinding{}
+	for _, page := range pages {
+		reported := map[string]bool{}
+		for _, ref := range htmlLinks(files[page].GetDataAsString()) {
+			if reported[ref] {
+				continue
+			}
+			target, host, ok := c.resolve(page, ref)
+			if !ok {
+				continue
+			}
+			if host != "" {
+				if len(c.allowedHosts) > 0 && !c.isAllowedHost(host) {
+					reported[ref] = true
+					findings = append(findings, c.finding(page, lintWarning, "external",
+						fmt.Sprintf("links to %s, whose host isn't allowed", ref)))
+				}
+				continue
+			}
+			if !isGenerated(files, target) {
+				reported[ref] = true
+				findings = append(findings, c.finding(page, lintError, "link",
+					fmt.Sprintf("links to %s, which isn't generated", ref)))
+			}
+		}
+	}
+	return findings
+}
+
+// Returns the path of the file relative to the target dir
+func (c *linkChecker) relPath(fc fs.FileContainer) string {
+	file := filepath.Join(fc.GetPath(), fc.GetFilename())
+	if rel, err := filepath.Rel(c.targetDir, file); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(file)
+}
+
+// Reports the finding for the page json the
+// page was rendered from, if there is one
+func (c *linkChecker) finding(page, severity, rule, msg string) lintFinding {
+	file := c.sources[page]
+	if file == "" {
+		file = filepath.Join(c.targetDir, filepath.FromSlash(page))
+	}
+	return lintFinding{
+		File:     file,
+		Severity: severity,
+		Rule:     rule,
+		Field:    "/" + page,
+		Message:  msg}
+}
+
+// Resolves the reference found within the given page to
+// the path of the referenced file below the target dir,
+// or to the host of an external link. References which
+// can't be checked, like anchors or mail links, aren't ok.
+func (c *linkChecker) resolve(page, ref string) (target, host string, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", "", false
+	}
+	switch u.Scheme {
+	case "", "http", "https":
+	default:
+		return "", "", false
+	}
+	if u.Host != "" {
+		h := strings.ToLower(u.Hostname())
+		if h != c.domain && h != "www."+c.domain {
+			return "", h, true
+		}
+		return strings.TrimPrefix(path.Clean("/"+u.Path), "/"), "", true
+	}
+	if u.Path == "" {
+		return "", "", false
+	}
+	pth := u.Path
+	if !strings.HasPrefix(pth, "/") {
+		pth = path.Join(path.Dir("/"+page), pth)
+	}
+	return strings.TrimPrefix(path.Clean(pth), "/"), "", true
+}
+
+func (c *linkChecker) isAllowedHost(host string) bool {
+	for _, allowed := range c.allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tells if the target is a generated file, or a dir
+// with an index.html, like the pages of the site
+func isGenerated(files map[string]fs.FileContainer, target string) bool {
+	if target == "." || target == "" {
+		_, ok := files["index.html"]
+		return ok
+	}
+	if _, ok := files[target]; ok {
+		return true
+	}
+	_, ok := files[path.Join(target, "index.html")]
+	return ok
+}
+
+// Returns the references of the href, src and srcset
+// attributes of the html, in the order of appearance
+func htmlLinks(content string) []string {
+	links := []string{}
+	for _, m := range linkAttrRegex.FindAllStringSubmatch(content, -1) {
+		value := html.UnescapeString(m[2] + m[3])
+		if strings.ToLower(m[1]) != "srcset" {
+			links = append(links, value)
+			continue
+		}
+		for _, candidate := range strings.Split(value, ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 {
+				links = append(links, fields[0])
+			}
+		}
+	}
+	return links
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+func givenFile(dir, filename, content string) fs.FileContainer {
+	fc := fs.NewFileContainer()
+	fc.SetPath(dir)
+	fc.SetFilename(filename)
+	fc.SetDataAsString(content)
+	return fc
+}
+
+func TestHtmlLinks(t *testing.T) {
+	links := htmlLinks(`<a href="/a/">a</a><img src='b.png' srcset="c.png 1x, /d.png 2x">` +
+		`<a data-href="/ignored/" href="/e/?x=1&amp;y=2">e</a>`)
+
+	expected := "/a/ b.png c.png /d.png /e/?x=1&y=2"
+	if actual := strings.Join(links, " "); actual != expected {
+		t.Error("Expected", expected, ", but got", actual)
+	}
+}
+
+func TestLinkCheckerFindsBrokenLinks(t *testing.T) {
+	c := NewLinkChecker("drewing.de", "deploy")
+	c.collectPost(&pageDoc{PathFromDocRoot: "/blog/post/", Filename: "index.html", file: "posts/doc00000.json"})
+
+	findings := c.Check([]fs.FileContainer{
+		givenFile("deploy", "index.html", `<a href="/blog/post/">post</a><a href="/missing/">missing</a>`),
+		givenFile("deploy/blog/post", "index.html", `
+			<a href="https://drewing.de/">home</a>
+			<a href="https://www.drewing.de/blog/post/index.html#top">self</a>
+			<img src="cover.png" srcset="cover.png 1x, cover-2x.png 2x">
+			<a href="../other/">other</a>
+			<a href="mailto:me@drewing.de">mail</a>
+			<a href="#comments">comments</a>
+			<a href="https://example.com/">external</a>`),
+		givenFile("deploy/blog/post", "cover.png", "png")})
+
+	actual := []string{}
+	for _, f := range findings {
+		actual = append(actual, f.String())
+	}
+	expected := []string{
+		"posts/doc00000.json: error: /blog/post/index.html: links to cover-2x.png, which isn't generated [link]",
+		"posts/doc00000.json: error: /blog/post/index.html: links to ../other/, which isn't generated [link]",
+		"deploy/index.html: error: /index.html: links to /missing/, which isn't generated [link]"}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Error("Expected", expected, ", but got", actual)
+	}
+}
+
+func TestLinkCheckerReportsExternalHostsNotAllowed(t *testing.T) {
+	c := NewLinkChecker("drewing.de", "deploy")
+	c.allowedHosts = []string{"github.com"}
+
+	findings := c.Check([]fs.FileContainer{
+		givenFile("deploy", "index.html",
+			`<a href="https://gist.github.com/x">gist</a><a href="http://example.com/">example</a>`)})
+
+	if len(findings) != 1 {
+		t.Fatal("Expected", 1, "finding, but got", findings)
+	}
+	if findings[0].Severity != lintWarning || !strings.Contains(findings[0].Message, "http://example.com/") {
+		t.Error("Expected a warning about example.com, but got", findings[0])
+	}
+}
+
+func TestSitesControllerChecksLinks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "links")
+	defer os.RemoveAll(dir)
+	postsDir := filepath.Join(dir, "posts")
+	os.MkdirAll(postsDir, 0755)
+	ioutil.WriteFile(filepath.Join(postsDir, "doc00000.md"), []byte(
+		"---\ntitle: Post\ncreate_date: 2009-06-13\npath: /blog/post/\n---\n"+
+			"[gone](/blog/gone/) and [archive](/blog/archive/)\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(fmt.Sprintf(`[{
+		"domain": "drewing.de",
+		"deploy": {"targetDir": %q, "cssFileName": "styles.css"},
+		"src": [{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog"}]
+	}]`, filepath.Join(dir, "deploy"), postsDir)), 0644)
+
+	sc := NewSitesController(staticPersistence.ReadConfig(dir, "config.json"))
+	findings, err := sc.CheckLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(findings) != 1 {
+		t.Fatal("Expected", 1, "finding, but got", findings)
+	}
+	if findings[0].File != filepath.Join(postsDir, "doc00000.md") {
+		t.Error("Expected the markdown file, but got", findings[0].File)
+	}
+	if exists, _ := fs.PathExists(filepath.Join(dir, "deploy")); exists {
+		t.Error("Expected no files to be written by the link check")
+	}
+}
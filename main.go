@@ -1,12 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
+	"fmt"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ingmardrewing/actions"
 	"github.com/ingmardrewing/fs"
@@ -21,28 +26,63 @@ var (
 	fmake       = false
 	fupdatejson = false
 	fstrato     = false
+	fdeploy     = false
+	fdryrun     = false
 	fclear      = false
+	fprune      = false
+	fserve      = false
+	fserveAddr  = ""
+	fjobs       = 1
+	fdrafts     = false
+	fcheck      = false
+	flint       = false
+	fchecklinks = false
+	fjson       = false
+	fdumpTpls   = ""
 	fconfigPath = ""
 	conf        []staticPersistence.Config
+	configDir   = "./testResources/"
+	settings    []siteSettings
+	settingsErr error
 	configFile  = "configNew.json"
 	debug       = false
 
 	generateSiteLocally = generateSiteLocallyFn
 	upload              = uploadFn
 	clear               = clearFn
+	addPosts            = addPostsFn
 	configureActions    = configureActionsFn
 	checkFlags          = checkFlagsFn
 	interactive         = interactiveFn
+	serve               = serveFn
+	checkConfig         = checkConfigFn
+	lint                = lintFn
+	checkLinks          = checkLinksFn
+	dumpTemplates       = dumpTemplatesFn
 	exit                = func() { os.Exit(0) }
+	fail                = func() { os.Exit(1) }
 )
 
 func init() {
 	flag.BoolVar(&fi, "i", false, "Interactive mode")
 	flag.BoolVar(&debug, "debug", false, "Run in debug mode")
 	flag.BoolVar(&fmake, "make", false, "Generate local site")
+	flag.BoolVar(&fprune, "prune", false, "Delete files of removed pages from the deploy dir")
+	flag.IntVar(&fjobs, "jobs", 1, "Number of sites, sources and contexts rendered in parallel")
+	flag.BoolVar(&fdrafts, "drafts", false, "Include drafts and scheduled pages, for local previews")
+	flag.BoolVar(&fcheck, "check", false, "Validate the config and the page json files it references")
+	flag.BoolVar(&flint, "lint", false, "Report problems within the page json files")
+	flag.BoolVar(&fchecklinks, "checklinks", false, "Render the sites without writing them and report broken internal links")
+	flag.BoolVar(&fjson, "json", false, "Print the findings of -lint and -checklinks as json")
+	flag.StringVar(&fdumpTpls, "dumptemplates", "", "Write the built-in page templates into the given dir, as a starting point for own templates")
 	flag.BoolVar(&fupdatejson, "updatejson", false, "Updates to new json format")
-	flag.BoolVar(&fstrato, "strato", false, "Upload site to strato")
+	flag.BoolVar(&fstrato, "strato", false, "Deprecated, same as -deploy")
+	flag.BoolVar(&fdeploy, "deploy", false, "Upload the files changed since the last upload")
+	flag.BoolVar(&fdryrun, "dryrun", false, "List the files -deploy would transfer without transferring them")
+	flag.BoolVar(&fadd, "add", false, "Create blog posts from the images in the addPostDir")
 	flag.BoolVar(&fclear, "clear", false, "Automatically publish the image in BLOG_DEFAULT_DIR and clear the dir afterwards")
+	flag.BoolVar(&fserve, "serve", false, "Serve the generated sites locally and rebuild them on changes")
+	flag.StringVar(&fserveAddr, "addr", "localhost:8080", "Address of the local preview server, further sites use the following ports")
 	flag.StringVar(&fconfigPath, "configPath", os.Getenv("BLOG_CONFIG_DIR"), "path to config file")
 	flag.Parse()
 
@@ -57,10 +97,12 @@ func init() {
 
 	exists, _ := fs.PathExists(path.Join(fconfigPath, configFile))
 	if exists {
-		conf = staticPersistence.ReadConfig(fconfigPath, configFile)
-	} else {
-		conf = staticPersistence.ReadConfig("./testResources/", configFile)
+		configDir = fconfigPath
 	}
+	conf = staticPersistence.ReadConfig(configDir, configFile)
+
+	// a broken config is reported by -check
+	settings, settingsErr = ReadSiteSettings(configDir, configFile)
 }
 
 func main() {
@@ -72,6 +114,9 @@ func main() {
 }
 
 func interactiveFn() {
+	if !settingsRead() {
+		return
+	}
 	a := configureActions()
 	for {
 		a.AskUser()
@@ -79,25 +124,210 @@ func interactiveFn() {
 }
 
 func checkFlagsFn() {
+	if fdrafts && (fstrato || fdeploy) {
+		log.Error("-drafts builds are for local previews and can't be deployed")
+		fail()
+		return
+	}
+	if fcheck {
+		checkConfig()
+	}
+	if !settingsRead() {
+		return
+	}
+	if flint {
+		lint()
+	}
+	if fchecklinks {
+		checkLinks()
+	}
+	if fdumpTpls != "" {
+		dumpTemplates()
+	}
+	if fadd {
+		addPosts()
+	}
 	if fmake {
 		generateSiteLocally()
 	}
 	if fupdatejson {
 		updateJsonFiles()
 	}
-	if fstrato {
+	if fstrato || fdeploy {
 		upload()
 	}
 	if fclear {
 		clear()
 	}
+	if fserve {
+		serve()
+	}
+}
+
+// Tells if the settings within the config could be read,
+// reporting the error otherwise, unless -check reported it
+func settingsRead() bool {
+	if settingsErr == nil {
+		return true
+	}
+	if !fcheck {
+		log.Error(settingsErr)
+		fail()
+	}
+	return false
 }
 
 func generateSiteLocallyFn() {
 	log.Debug("main:generateSiteLocallyFn")
 	log.Debug(conf)
+	sc := newSitesControllerFromFlags()
+	if err := sc.UpdateStaticSites(); err != nil {
+		log.Error(err)
+	}
+}
+
+// Creates a sitesController using the build
+// options given on the command line
+func newSitesControllerFromFlags() *sitesController {
 	sc := NewSitesController(conf)
-	sc.UpdateStaticSites()
+	sc.settings = settings
+	sc.options.prune = fprune
+	sc.options.jobs = fjobs
+	sc.options.drafts = fdrafts
+	return sc
+}
+
+func checkConfigFn() {
+	log.Debug("main:checkConfigFn")
+	checker := NewConfigChecker(path.Join(configDir, configFile), conf)
+	problems := checker.Check()
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problem(s) found\n", len(problems))
+		fail()
+		return
+	}
+	fmt.Println("config ok")
+}
+
+func lintFn() {
+	log.Debug("main:lintFn")
+	findings := []lintFinding{}
+	for _, config := range conf {
+		findings = append(findings, NewPageLinter(config).Lint()...)
+	}
+
+	printFindings(findings)
+	if countFindings(findings, lintError) > 0 {
+		fail()
+	}
+}
+
+func checkLinksFn() {
+	log.Debug("main:checkLinksFn")
+	findings, err := newSitesControllerFromFlags().CheckLinks()
+	if err != nil {
+		log.Error(err)
+	}
+	printFindings(findings)
+	if err != nil || countFindings(findings, lintError) > 0 {
+		fail()
+	}
+}
+
+func dumpTemplatesFn() {
+	log.Debug("main:dumpTemplatesFn")
+	files, err := DumpPageTemplates(fdumpTpls)
+	for _, f := range files {
+		fmt.Println("Created", f)
+	}
+	if err != nil {
+		log.Error(err)
+		fail()
+	}
+}
+
+// Prints the findings of -lint or -checklinks,
+// as json if -json is given
+func printFindings(findings []lintFinding) {
+	if fjson {
+		data, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+	fmt.Printf("%d error(s), %d warning(s)\n",
+		countFindings(findings, lintError),
+		countFindings(findings, lintWarning))
+}
+
+func serveFn() {
+	log.Debug("main:serveFn")
+	host, portStr, err := net.SplitHostPort(fserveAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sc := newSitesControllerFromFlags()
+	if err := sc.UpdateStaticSites(); err != nil {
+		log.Error(err)
+	}
+
+	for i, config := range conf {
+		addr := net.JoinHostPort(host, strconv.Itoa(port+i))
+		go watchSite(sc, i)
+		go NewPreviewServer(config, addr).ListenAndServe()
+		fmt.Printf("Serving %s at http://%s/\n", config.Domain, addr)
+	}
+	select {}
+}
+
+// Rebuilds the site of the given index whenever a page
+// json or markdown file within one of its source dirs,
+// or one of its templates, theme files or assets changes
+func watchSite(sc *sitesController, site int) {
+	config := sc.configs[site]
+	settings := settingsAt(sc.settings, site)
+	dirs := []string{}
+	assets := []string{}
+	for i, src := range config.Src {
+		dirs = append(dirs, src.Dir)
+		for _, name := range settings.srcAt(i).assetDirs() {
+			assets = append(assets, filepath.Join(src.Dir, name))
+		}
+	}
+	w := NewDirWatcher(500*time.Millisecond, dirs...)
+	w.addTrees(assets...)
+	if settings.Static != "" {
+		w.addTrees(settings.Static)
+	}
+	if settings.Templates != "" {
+		w.addTrees(settings.Templates)
+	}
+	if settings.Theme != "" {
+		themesDir := settings.ThemesDir
+		if themesDir == "" {
+			themesDir = defaultThemesDir
+		}
+		w.addTrees(themesDir)
+	}
+	w.Watch(func() {
+		fmt.Println("Rebuilding", config.Domain)
+		if err := sc.UpdateStaticSite(site); err != nil {
+			log.Error(err)
+		}
+	})
 }
 
 func updateJsonFiles() {
@@ -118,8 +348,12 @@ func configureActionsFn() actions.Choice {
 		generateSiteLocally)
 	c.AddAction(
 		"upload",
-		"Upload generated html, css and js to strato (www.drewing.de)",
+		"Upload generated html, css and js changed since the last upload",
 		upload)
+	c.AddAction(
+		"add",
+		"Create blog posts from the images in the add post dir",
+		addPosts)
 	c.AddAction(
 		"clear",
 		"clear auto blog dir",
@@ -146,9 +380,26 @@ func inferBlogTitlePlain(filename string) string {
 	return strings.ToLower(dashSeparated)
 }
 
+func addPostsFn() {
+	for _, config := range conf {
+		if config.AddPostDir == "" {
+			continue
+		}
+		files, err := NewPostAdder(config).AddPosts()
+		for _, f := range files {
+			fmt.Println("Created", f)
+		}
+		if err != nil {
+			log.Error(config.Domain, ": ", err)
+		}
+	}
+}
+
 func clearFn() {
 	c := newCommand("cleardir.pl")
-	c.run()
+	if err := c.run(); err != nil {
+		log.Error(err)
+	}
 }
 
 func askUserForTitle() (string, string) {
@@ -158,6 +409,19 @@ func askUserForTitle() (string, string) {
 }
 
 func uploadFn() {
-	c := newCommand("blogUpload.pl")
-	c.run()
+	for i, config := range conf {
+		upl := settingsAt(settings, i).Deploy.Upload
+		d, err := NewDeployer(upl)
+		if err != nil {
+			log.Error(config.Domain, ": ", err)
+			continue
+		}
+
+		fmt.Println("Deploying", config.Domain)
+		dp := NewDeployment(config.Deploy.TargetDir, upl.RemoteDir, d)
+		dp.dryRun = fdryrun
+		if err := dp.Run(); err != nil {
+			log.Error(config.Domain, ": ", err)
+		}
+	}
 }
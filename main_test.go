@@ -1,8 +1,10 @@
 package main
 
 import (
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime"
 	"testing"
 
@@ -42,12 +44,14 @@ func TestMainInternals(t *testing.T) {
 
 func setup() {
 	conf = staticPersistence.ReadConfig("testResources/", "configNew.json")
+	settings, _ = ReadSiteSettings("testResources/", "configNew.json")
 	log.SetLevel(log.DebugLevel)
 }
 
 func tearDown() {
 	filepath := path.Join(getTestFileDirPath(), conf[0].Deploy.TargetDir)
 	fs.RemoveDirContents(filepath)
+	os.Remove(manifestPath(filepath))
 }
 
 func getTestFileDirPath() string {
@@ -77,6 +81,12 @@ func TestConfigureActions(t *testing.T) {
 		t.Error("Expected action using upload, but it doesn't.")
 	}
 
+	actionFunction = findActionByName("add", as).GetFunction()
+	fn = addPosts
+	if &actionFunction == &fn {
+		t.Error("Expected action using addPosts, but it doesn't.")
+	}
+
 	actionFunction = findActionByName("clear", as).GetFunction()
 	fn = clear
 	if &actionFunction == &fn {
@@ -110,11 +120,11 @@ func TestGenSite(t *testing.T) {
 	deployDir := path.Join(getTestFileDirPath(),
 		conf[0].Deploy.TargetDir)
 
-	cssPath := path.Join(deployDir, "styles.css")
-	cssFileExists, _ := fs.PathExists(cssPath)
+	cssPath := path.Join(deployDir, "styles.*.css")
+	cssFiles, _ := filepath.Glob(cssPath)
 
-	if !cssFileExists {
-		t.Error("No css file found at:", cssPath)
+	if len(cssFiles) != 1 {
+		t.Error("No css bundle found at:", cssPath)
 	}
 
 	indexPath := path.Join(deployDir, "blog", "index.html")
@@ -197,3 +207,45 @@ func TestCheckFlags(t *testing.T) {
 		t.Error("checkFlags did not trigger all expected functions.")
 	}
 }
+
+func TestCheckFlagsReportsBrokenSettings(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "config")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(
+		`[{"domain": "drewing.de", "src": [{"pageSize": "ten"}]}]`), 0644)
+
+	oldDir, oldFile, oldSettings := configDir, configFile, settings
+	defer func() {
+		configDir, configFile, settings, settingsErr = oldDir, oldFile, oldSettings, nil
+		checkConfig, generateSiteLocally = checkConfigFn, generateSiteLocallyFn
+		fail = func() { os.Exit(1) }
+		fcheck, fmake = false, false
+	}()
+	configDir, configFile = dir, "config.json"
+	settings, settingsErr = ReadSiteSettings(dir, "config.json")
+	if settingsErr == nil {
+		t.Fatal("Expected the settings not to be readable")
+	}
+
+	failed, generated := 0, false
+	fail = func() { failed++ }
+	generateSiteLocally = func() { generated = true }
+	fcheck, fmake, fstrato, fclear = true, true, false, false
+
+	checkFlagsFn()
+
+	if failed != 1 {
+		t.Error("Expected -check to report the broken config once, but got", failed)
+	}
+	if generated {
+		t.Error("Expected no site to be generated from a broken config")
+	}
+
+	failed = 0
+	fcheck = false
+	checkFlagsFn()
+
+	if failed != 1 || generated {
+		t.Error("Expected the broken settings to be reported without -check")
+	}
+}
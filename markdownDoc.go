@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	"gopkg.in/yaml.v3"
+)
+
+// Renders the markdown of pages to html. Raw html
+// is passed through, like in the content of page json.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(html.WithUnsafe()))
+
+// The front matter of a markdown page, either yaml
+// enclosed by --- lines or toml enclosed by +++ lines
+type frontMatter struct {
+	Title       string      `yaml:"title" toml:"title"`
+	TitlePlain  string      `yaml:"title_plain" toml:"title_plain"`
+	Excerpt     string      `yaml:"excerpt" toml:"excerpt"`
+	Category    string      `yaml:"category" toml:"category"`
+	Tags        []string    `yaml:"tags" toml:"tags"`
+	CreateDate  interface{} `yaml:"create_date" toml:"create_date"`
+	Path        string      `yaml:"path" toml:"path"`
+	Filename    string      `yaml:"filename" toml:"filename"`
+	Images      []imageUrls `yaml:"images" toml:"images"`
+	Draft       bool        `yaml:"draft" toml:"draft"`
+	Featured    bool        `yaml:"featured" toml:"featured"`
+	PublishDate interface{} `yaml:"publish_date" toml:"publish_date"`
+}
+
+// Tells if the file is a markdown page
+func isMarkdownFile(file string) bool {
+	return filepath.Ext(file) == ".md"
+}
+
+// Reads a markdown page and turns it into the
+// page document the equivalent page json describes
+func readMarkdownDoc(file string) (*pageDoc, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, &pageDocError{file, err.Error()}
+	}
+	fm, body, err := parseFrontMatter(data)
+	if err != nil {
+		return nil, &pageDocError{file, err.Error()}
+	}
+	if fm.Path == "" {
+		return nil, &pageDocError{file, "path is missing in the front matter"}
+	}
+	date, err := frontMatterDate("create_date", fm.CreateDate)
+	if err != nil {
+		return nil, &pageDocError{file, err.Error()}
+	}
+	publishDate, err := frontMatterDate("publish_date", fm.PublishDate)
+	if err != nil {
+		return nil, &pageDocError{file, err.Error()}
+	}
+
+	content := new(bytes.Buffer)
+	if err := markdown.Convert(body, content); err != nil {
+		return nil, &pageDocError{file, err.Error()}
+	}
+
+	doc := &pageDoc{
+		Version:         2,
+		Filename:        fm.Filename,
+		PathFromDocRoot: fm.Path,
+		Category:        fm.Category,
+		Tags:            docTags(fm.Tags),
+		CreateDate:      date,
+		Title:           fm.Title,
+		TitlePlain:      fm.TitlePlain,
+		Excerpt:         fm.Excerpt,
+		Content:         strings.TrimSpace(content.String()),
+		ImagesUrls:      fm.Images,
+		Draft:           fm.Draft,
+		Featured:        fm.Featured,
+		PublishDate:     publishDate,
+		file:            file}
+	if doc.Filename == "" {
+		doc.Filename = "index.html"
+	}
+	if doc.Tags == nil {
+		doc.Tags = docTags{}
+	}
+	if doc.ImagesUrls == nil {
+		doc.ImagesUrls = []imageUrls{}
+	}
+	return doc, nil
+}
+
+// Splits the front matter from the markdown and decodes it.
+// Unknown keys are reported, as they are most likely typos.
+func parseFrontMatter(data []byte) (*frontMatter, []byte, error) {
+	lines := strings.SplitAfter(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	delimiter := strings.TrimSpace(lines[0])
+	if delimiter != "---" && delimiter != "+++" {
+		return nil, nil, fmt.Errorf("front matter is missing, expected --- or +++ in the first line")
+	}
+	end := 1
+	for end < len(lines) && strings.TrimSpace(lines[end]) != delimiter {
+		end++
+	}
+	if end == len(lines) {
+		return nil, nil, fmt.Errorf("front matter is not closed by %s", delimiter)
+	}
+	head := strings.Join(lines[1:end], "")
+	body := []byte(strings.Join(lines[end+1:], ""))
+
+	fm := new(frontMatter)
+	if delimiter == "---" {
+		dec := yaml.NewDecoder(strings.NewReader(head))
+		dec.KnownFields(true)
+		if err := dec.Decode(fm); err != nil && err != io.EOF {
+			return nil, nil, fmt.Errorf("front matter: %v", err)
+		}
+		return fm, body, nil
+	}
+
+	meta, err := toml.Decode(head, fm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("front matter: %v", err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, nil, fmt.Errorf("front matter: unknown key %s", undecoded[0])
+	}
+	return fm, body, nil
+}
+
+// Returns a date of the front matter in the form of
+// page json, dates may be quoted or not
+func frontMatterDate(field string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 {
+			return v.Format("2006-01-02"), nil
+		}
+		return v.Format(time.RFC3339), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("%s %v is not a date", field, value)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/staticIntf"
+)
+
+const jsonPage = `{
+	"version": 2,
+	"filename": "index.html",
+	"path_from_doc_root": "/blog/2019/01/31/markdown/",
+	"category": "blog post",
+	"tags": ["go", "static"],
+	"create_date": "2019-01-31",
+	"title": "Markdown",
+	"title_plain": "markdown",
+	"excerpt": "Writing pages in markdown",
+	"content": "<h1>Markdown</h1>\n<p>Some <em>emphasized</em> text.</p>",
+	"thumb_base64": "",
+	"images_urls": [{"title": "Markdown", "w_190": "", "w_390": "", "w_800": "https://drewing.de/blog/md-w800.png", "max_resolution": ""}]
+}`
+
+const yamlPage = `---
+title: Markdown
+title_plain: markdown
+excerpt: Writing pages in markdown
+category: blog post
+tags: [go, static]
+create_date: 2019-01-31
+path: /blog/2019/01/31/markdown/
+images:
+  - title: Markdown
+    w_800: https://drewing.de/blog/md-w800.png
+---
+# Markdown
+
+Some *emphasized* text.
+`
+
+const tomlPage = `+++
+title = "Markdown"
+title_plain = "markdown"
+excerpt = "Writing pages in markdown"
+category = "blog post"
+tags = ["go", "static"]
+create_date = 2019-01-31
+path = "/blog/2019/01/31/markdown/"
+
+[[images]]
+title = "Markdown"
+w_800 = "https://drewing.de/blog/md-w800.png"
++++
+# Markdown
+
+Some *emphasized* text.
+`
+
+func givenPageFiles(t *testing.T, files map[string]string) string {
+	dir, _ := ioutil.TempDir("", "pages")
+	for name, content := range files {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	return dir
+}
+
+// Loads the page dtos of a dir holding only the given file
+func givenPageDtos(t *testing.T, name, content string, transforms ...pageTransform) []staticIntf.PageDto {
+	dir := givenPageFiles(t, map[string]string{name: content})
+	defer os.RemoveAll(dir)
+	l := NewPageLoader(dir, transforms...)
+	dtos := l.Load()
+	if l.Err() != nil {
+		t.Fatal(l.Err())
+	}
+	return dtos
+}
+
+func TestMarkdownDocEqualsPageJson(t *testing.T) {
+	expected := givenPageDtos(t, "doc00000.json", jsonPage)
+	if len(expected) != 1 {
+		t.Fatal("Expected the dto of the page json, but got", expected)
+	}
+	for name, content := range map[string]string{"doc00001.md": yamlPage, "doc00002.md": tomlPage} {
+		actual := givenPageDtos(t, name, content)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Error("Expected the dto of", name, "to equal that of the page json", expected, ", but got", actual)
+		}
+	}
+}
+
+func TestTransformedPageJsonKeepsItsFields(t *testing.T) {
+	dir := givenPageFiles(t, map[string]string{"doc00000.json": `{
+		"version": 1,
+		"thumbImg": "https://devabo.de/thumb.png",
+		"url": "https://devabo.de/2013/08/01/a-step-in-the-dark/",
+		"dsq_thread_id": "8 http://devabo.de/?p=8",
+		"tags": "go, static",
+		"title": "A step",
+		"content": "Act I"}`})
+	defer os.RemoveAll(dir)
+	retitle := func(doc *pageDoc) (bool, error) {
+		doc.Title = "A step in the dark"
+		return true, nil
+	}
+
+	l := NewPageLoader(dir, retitle)
+	data, changed, err := l.loadFile(filepath.Join(dir, "doc00000.json"), l.transforms)
+	if err != nil || !changed {
+		t.Fatal("Expected the changed page json, but got", changed, err)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"version":       1.0,
+		"thumbImg":      "https://devabo.de/thumb.png",
+		"url":           "https://devabo.de/2013/08/01/a-step-in-the-dark/",
+		"dsq_thread_id": "8 http://devabo.de/?p=8",
+		"tags":          "go, static",
+		"title":         "A step in the dark",
+		"content":       "Act I"}
+	if !reflect.DeepEqual(expected, fields) {
+		t.Error("Expected", expected, ", but got", fields)
+	}
+}
+
+func TestMarkdownDocErrors(t *testing.T) {
+	cases := map[string]string{
+		"missing front matter":  "# Markdown\n",
+		"unclosed front matter": "---\ntitle: Markdown\n",
+		"missing path":          "---\ntitle: Markdown\n---\ntext\n",
+		"unknown yaml key":      "---\npath: /a/\ntitel: Markdown\n---\ntext\n",
+		"unknown toml key":      "+++\npath = \"/a/\"\ntitel = \"Markdown\"\n+++\ntext\n"}
+	for name, content := range cases {
+		dir := givenPageFiles(t, map[string]string{"doc.md": content})
+		_, err := readMarkdownDoc(filepath.Join(dir, "doc.md"))
+		if err == nil {
+			t.Error("Expected an error for", name)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPageLoaderMixesJsonAndMarkdown(t *testing.T) {
+	dir := givenPageFiles(t, map[string]string{
+		"doc00000.json": jsonPage,
+		"doc00001.md":   yamlPage})
+	defer os.RemoveAll(dir)
+
+	l := NewPageLoader(dir)
+	dtos := l.Load()
+	if l.Err() != nil {
+		t.Fatal(l.Err())
+	}
+	if len(dtos) != 2 {
+		t.Error("Expected 2 page dtos, but got", len(dtos))
+	}
+}
+
+func TestPageLoaderRejectsDuplicateNames(t *testing.T) {
+	dir := givenPageFiles(t, map[string]string{
+		"doc00000.json": jsonPage,
+		"doc00000.md":   yamlPage})
+	defer os.RemoveAll(dir)
+
+	l := NewPageLoader(dir)
+	l.Load()
+	if l.Err() == nil {
+		t.Error("Expected an error for doc00000.md shadowing doc00000.json")
+	}
+}
+
+func TestPageLoaderRejectsDuplicateNamesOfDrafts(t *testing.T) {
+	dir := givenPageFiles(t, map[string]string{
+		"doc00000.json": strings.Replace(jsonPage, `"version": 2,`, `"version": 2, "draft": true,`, 1),
+		"doc00000.md":   yamlPage})
+	defer os.RemoveAll(dir)
+
+	l := NewPageLoader(dir)
+	l.Load()
+	if l.Err() == nil {
+		t.Error("Expected an error for doc00000.md shadowing the draft doc00000.json")
+	}
+}
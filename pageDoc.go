@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Reads all page json and markdown files of the given
+// dir, sorted by filename. Files which can't be parsed
+// are skipped and reported by the returned errors.
+func readPageDocs(dir string) ([]*pageDoc, []error) {
+	files := []string{}
+	for _, pattern := range []string{"*.json", "*.md"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, []error{err}
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	docs := []*pageDoc{}
+	errs := []error{}
+	for _, file := range files {
+		doc, err := readPageDoc(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs, errs
+}
+
+// Reads a single page json or markdown file
+func readPageDoc(file string) (*pageDoc, error) {
+	if isMarkdownFile(file) {
+		return readMarkdownDoc(file)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, &pageDocError{file, err.Error()}
+	}
+	doc := new(pageDoc)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, &pageDocError{file, jsonErrorPosition(data, err)}
+	}
+	doc.file = file
+	return doc, nil
+}
+
+// Error returned for page json files
+// which can't be read
+type pageDocError struct {
+	file string
+	msg  string
+}
+
+func (e *pageDocError) Error() string {
+	return e.file + ": " + e.msg
+}
+
+// Encodes the page json in the format of the existing
+// files, without escaping the html of the content
+func encodePageDoc(doc *pageDoc) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	err := enc.Encode(doc)
+	return buf.Bytes(), err
+}
+
+// Patches the fields which differ between the encodings of
+// a document before and after a change into its page json,
+// leaving the fields pageDoc doesn't know as they are
+func patchPageJson(data, before, after []byte) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	old, changed := map[string]json.RawMessage{}, map[string]json.RawMessage{}
+	if err := json.Unmarshal(before, &old); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &changed); err != nil {
+		return nil, err
+	}
+	for key, value := range changed {
+		if !bytes.Equal(old[key], value) {
+			fields[key] = value
+		}
+	}
+	for key := range old {
+		if _, ok := changed[key]; !ok {
+			delete(fields, key)
+		}
+	}
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	err := enc.Encode(fields)
+	return buf.Bytes(), err
+}
+
+// Adds the line and column to json syntax errors
+func jsonErrorPosition(data []byte, err error) string {
+	offset := int64(-1)
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	}
+	if offset < 0 || offset > int64(len(data)) {
+		return err.Error()
+	}
+	before := string(data[:offset])
+	line := strings.Count(before, "\n") + 1
+	column := len(before) - strings.LastIndex(before, "\n")
+	return fmt.Sprintf("line %d, column %d: %v", line, column, err)
+}
+
+// The content of a page json file as stored on
+// disk, before it is turned into a staticIntf.PageDto
+type pageDoc struct {
+	Version         int         `json:"version"`
+	Filename        string      `json:"filename"`
+	PathFromDocRoot string      `json:"path_from_doc_root"`
+	Category        string      `json:"category"`
+	Tags            docTags     `json:"tags"`
+	CreateDate      string      `json:"create_date"`
+	Title           string      `json:"title"`
+	TitlePlain      string      `json:"title_plain"`
+	Excerpt         string      `json:"excerpt"`
+	Content         string      `json:"content"`
+	ThumbBase64     string      `json:"thumb_base64"`
+	ImagesUrls      []imageUrls `json:"images_urls"`
+	Draft           bool        `json:"draft,omitempty"`
+	Featured        bool        `json:"featured,omitempty"`
+	PublishDate     string      `json:"publish_date,omitempty"`
+
+	// the date and location of version 1 documents
+	Date string `json:"date,omitempty"`
+	Url  string `json:"url,omitempty"`
+
+	file string
+}
+
+// The image variants of a page
+type imageUrls struct {
+	Title         string `json:"title" yaml:"title" toml:"title"`
+	W190          string `json:"w_190" yaml:"w_190" toml:"w_190"`
+	W390          string `json:"w_390" yaml:"w_390" toml:"w_390"`
+	W800          string `json:"w_800" yaml:"w_800" toml:"w_800"`
+	MaxResolution string `json:"max_resolution" yaml:"max_resolution" toml:"max_resolution"`
+}
+
+// Tags are stored either as json array or,
+// in older documents, as comma separated string
+type docTags []string
+
+func (t *docTags) UnmarshalJSON(data []byte) error {
+	list := []string{}
+	if err := json.Unmarshal(data, &list); err == nil {
+		*t = list
+		return nil
+	}
+	joined := ""
+	if err := json.Unmarshal(data, &joined); err != nil {
+		return err
+	}
+	*t = docTags{}
+	for _, tag := range strings.Split(joined, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
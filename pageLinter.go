@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+const (
+	lintError   = "error"
+	lintWarning = "warning"
+)
+
+// Creates a pageLinter checking the page json
+// files of all sources of the given site config
+func NewPageLinter(config staticPersistence.Config) *pageLinter {
+	l := new(pageLinter)
+	l.config = config
+	l.assetRegex = regexp.MustCompile(`(?:src|srcset)\s*=\s*"([^"]*)"`)
+	return l
+}
+
+// A single finding of the linter
+type lintFinding struct {
+	File     string `json:"file"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (f lintFinding) String() string {
+	field := ""
+	if f.Field != "" {
+		field = f.Field + ": "
+	}
+	return fmt.Sprintf("%s: %s: %s%s [%s]", f.File, f.Severity, field, f.Message, f.Rule)
+}
+
+// The pageLinter reports problems within page json
+// files before they are rendered
+type pageLinter struct {
+	config     staticPersistence.Config
+	assetRegex *regexp.Regexp
+	findings   []lintFinding
+	urls       map[string]string
+}
+
+// Lints all page json files of the site and
+// returns the findings in file order
+func (l *pageLinter) Lint() []lintFinding {
+	l.findings = []lintFinding{}
+	l.urls = map[string]string{}
+
+	linted := map[string]bool{}
+	for _, src := range l.config.Src {
+		dir := filepath.Clean(src.Dir)
+		if linted[dir] {
+			continue
+		}
+		linted[dir] = true
+
+		docs, errs := readPageDocs(dir)
+		for _, err := range errs {
+			if docErr, ok := err.(*pageDocError); ok {
+				l.report(docErr.file, lintError, "invalid-json", "", "%s", docErr.msg)
+			} else {
+				l.report(dir, lintError, "invalid-json", "", "%v", err)
+			}
+		}
+		for _, doc := range docs {
+			l.lintDoc(doc)
+		}
+	}
+	return l.findings
+}
+
+func (l *pageLinter) report(file, severity, rule, field, msg string, args ...interface{}) {
+	l.findings = append(l.findings, lintFinding{
+		file, severity, rule, field, fmt.Sprintf(msg, args...)})
+}
+
+func (l *pageLinter) lintDoc(doc *pageDoc) {
+	if strings.TrimSpace(doc.Title) == "" {
+		l.report(doc.file, lintError, "missing-title", "title", "is empty")
+	}
+	if doc.Version != 2 {
+		l.report(doc.file, lintWarning, "version", "version",
+			"is %d, only version 2 documents are fully supported", doc.Version)
+	}
+
+	l.lintDate(doc)
+	l.lintPath(doc)
+	l.lintImages(doc)
+	l.lintContentAssets(doc)
+}
+
+func (l *pageLinter) lintDate(doc *pageDoc) {
+	if doc.PublishDate != "" {
+		if _, err := parsePublishDate(doc.PublishDate); err != nil {
+			l.report(doc.file, lintError, "date", "publish_date", "%v", err)
+		}
+	}
+	if doc.CreateDate == "" && doc.Version < 2 {
+		l.lintLegacyDate(doc)
+		return
+	}
+	if doc.CreateDate == "" {
+		l.report(doc.file, lintError, "date", "create_date", "is empty")
+		return
+	}
+	if _, err := time.Parse("2006-01-02", doc.CreateDate); err != nil {
+		l.report(doc.file, lintError, "date", "create_date",
+			"%q is not a valid date of the form YYYY-MM-DD", doc.CreateDate)
+	}
+}
+
+// Version 1 documents hold their date in the format
+// of the feeds of the blogs they were exported from
+func (l *pageLinter) lintLegacyDate(doc *pageDoc) {
+	if doc.Date == "" {
+		l.report(doc.file, lintError, "date", "date", "is empty")
+		return
+	}
+	if _, err := time.Parse(time.RFC1123Z, doc.Date); err != nil {
+		l.report(doc.file, lintError, "date", "date",
+			"%q is not a valid date of the form %s", doc.Date, time.RFC1123Z)
+	}
+}
+
+// Reports pages without location, and pages which would
+// be written to the same location as a page linted before
+func (l *pageLinter) lintPath(doc *pageDoc) {
+	field, dir := "path_from_doc_root", doc.PathFromDocRoot
+	if dir == "" && doc.Version < 2 && doc.Url != "" {
+		// version 1 documents are located by their url
+		field = "url"
+		u, err := url.Parse(doc.Url)
+		if err != nil {
+			l.report(doc.file, lintError, "missing-path", field, "%v", err)
+			return
+		}
+		dir = u.Path
+	}
+	if dir == "" {
+		l.report(doc.file, lintError, "missing-path", field, "is empty")
+		return
+	}
+	location := path.Join(dir, doc.Filename)
+	if first, ok := l.urls[location]; ok {
+		l.report(doc.file, lintError, "duplicate-path", field,
+			"%s is also used by %s", location, first)
+		return
+	}
+	l.urls[location] = doc.file
+}
+
+func (l *pageLinter) lintImages(doc *pageDoc) {
+	for i, img := range doc.ImagesUrls {
+		if isLocalImage(img.MaxResolution) {
+			// the variants are generated by the imagePipeline
+			continue
+		}
+		variants := []struct{ name, url string }{
+			{"w_190", img.W190},
+			{"w_390", img.W390},
+			{"w_800", img.W800}}
+		for _, v := range variants {
+			field := fmt.Sprintf("images_urls[%d].%s", i, v.name)
+			if v.url == "" {
+				l.report(doc.file, lintWarning, "empty-image-variant", field, "is empty")
+			} else {
+				l.lintAssetUrl(doc, field, v.url)
+			}
+		}
+		if img.MaxResolution != "" {
+			l.lintAssetUrl(doc, fmt.Sprintf("images_urls[%d].max_resolution", i), img.MaxResolution)
+		}
+	}
+}
+
+// Reports assets referenced by src and srcset
+// attributes within the content
+func (l *pageLinter) lintContentAssets(doc *pageDoc) {
+	reported := map[string]bool{}
+	for _, match := range l.assetRegex.FindAllStringSubmatch(doc.Content, -1) {
+		for _, candidate := range strings.Split(match[1], ",") {
+			fields := strings.Fields(candidate)
+			if len(fields) == 0 || reported[fields[0]] {
+				continue
+			}
+			reported[fields[0]] = true
+			l.lintAssetUrl(doc, "content", fields[0])
+		}
+	}
+}
+
+func (l *pageLinter) lintAssetUrl(doc *pageDoc, field, url string) {
+	if strings.HasPrefix(url, "http://") {
+		l.report(doc.file, lintWarning, "insecure-asset", field,
+			"%s is loaded via http on the https site %s", url, l.config.Domain)
+	}
+}
+
+// Counts the findings of the given severity
+func countFindings(findings []lintFinding, severity string) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
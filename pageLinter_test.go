@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+)
+
+func findingsFor(findings []lintFinding, file, rule string) []lintFinding {
+	matching := []lintFinding{}
+	for _, f := range findings {
+		if f.File == file && f.Rule == rule {
+			matching = append(matching, f)
+		}
+	}
+	return matching
+}
+
+func TestPageLinter(t *testing.T) {
+	findings := NewPageLinter(conf[0]).Lint()
+
+	cases := []struct {
+		file, rule, field string
+	}{
+		{"testResources/src/marginal/doc00003.json", "date", "create_date"},
+		{"testResources/src/portfolio/doc00000.json", "duplicate-path", "path_from_doc_root"},
+		{"testResources/src/posts/doc00000.json", "empty-image-variant", "images_urls[0].w_190"},
+		{"testResources/src/posts/doc00000.json", "insecure-asset", "images_urls[0].w_390"},
+		{"testResources/src/posts/doc00000.json", "insecure-asset", "content"},
+		{"testResources/src/narrative/doc00000.json", "version", "version"}}
+
+	for _, c := range cases {
+		matching := findingsFor(findings, c.file, c.rule)
+		found := false
+		for _, f := range matching {
+			found = found || f.Field == c.field
+		}
+		if !found {
+			t.Error("Expected", c.rule, "finding for", c.field, "in", c.file, ", but got", matching)
+		}
+	}
+}
+
+func TestPageLinterReportsDuplicatesOnlyOnce(t *testing.T) {
+	findings := NewPageLinter(conf[0]).Lint()
+
+	// the marginal dir is configured twice, but
+	// must be linted only once
+	matching := findingsFor(findings, "testResources/src/marginal/doc00000.json", "duplicate-path")
+	if len(matching) != 0 {
+		t.Error("Expected no duplicate for a dir configured twice, but got", matching)
+	}
+}
+
+func TestPageLinterReportsMissingPath(t *testing.T) {
+	l := NewPageLinter(conf[0])
+	l.lintPath(&pageDoc{file: "a.json"})
+	l.lintPath(&pageDoc{file: "b.json"})
+
+	if len(l.findings) != 2 || findingsFor(l.findings, "b.json", "missing-path") == nil {
+		t.Error("Expected a missing-path finding for each page, but got", l.findings)
+	}
+}
+
+func TestPageLinterChecksVersion1Docs(t *testing.T) {
+	l := NewPageLinter(conf[0])
+	l.urls = map[string]string{}
+	l.lintDoc(&pageDoc{file: "a.json", Version: 1, Title: "A", Filename: "index.html",
+		Date: "Thu, 01 Aug 2013 20:00:00 +0200", Url: "https://devabo.de/2013/08/01/a/"})
+	l.lintDoc(&pageDoc{file: "b.json", Version: 1, Title: "B", Filename: "index.html",
+		Date: "2013-08-01", Url: "https://devabo.de/2013/08/01/a/"})
+
+	cases := []struct {
+		file, rule, field string
+		expected          int
+	}{
+		{"a.json", "version", "version", 1},
+		{"a.json", "date", "date", 0},
+		{"a.json", "duplicate-path", "url", 0},
+		{"b.json", "version", "version", 1},
+		{"b.json", "date", "date", 1},
+		{"b.json", "duplicate-path", "url", 1}}
+	for _, c := range cases {
+		matching := findingsFor(l.findings, c.file, c.rule)
+		if len(matching) != c.expected || (c.expected > 0 && matching[0].Field != c.field) {
+			t.Error("Expected", c.expected, c.rule, "findings for", c.field, "in", c.file, ", but got", matching)
+		}
+	}
+}
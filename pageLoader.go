@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ingmardrewing/staticIntf"
+	"github.com/ingmardrewing/staticPersistence"
+	log "github.com/sirupsen/logrus"
+)
+
+// Changes a page document before it is turned into a
+// dto, returns true if the document has been changed
+type pageTransform func(doc *pageDoc) (bool, error)
+
+// Creates a pageLoader for the page json files
+// within the given dir
+func NewPageLoader(dir string, transforms ...pageTransform) *pageLoader {
+	l := new(pageLoader)
+	l.dir = dir
+	l.transforms = transforms
+	l.publication = NewPublication(time.Now(), false)
+	return l
+}
+
+// The pageLoader reads the page dtos of a source dir.
+// If a transform changes a document, or the dir contains
+// markdown pages, the documents are staged as page json
+// into a temporary dir, which is then read by
+// staticPersistence. Pages which aren't published are
+// left out. Other files and dirs, like the assets dir,
+// are ignored.
+type pageLoader struct {
+	dir         string
+	transforms  []pageTransform
+	publication *publication
+	err         error
+}
+
+// Returns the error of the last call of Load
+func (l *pageLoader) Err() error {
+	return l.err
+}
+
+// Reads the page dtos, applying the given transforms after
+// those of the loader. Returns nil if they can't be read,
+// see Err.
+func (l *pageLoader) Load(transforms ...pageTransform) []staticIntf.PageDto {
+	all := append(append([]pageTransform{}, l.transforms...), transforms...)
+	dtos, err := l.load(all)
+	l.err = err
+	return dtos
+}
+
+func (l *pageLoader) load(transforms []pageTransform) ([]staticIntf.PageDto, error) {
+	infos, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	staged := map[string][]byte{}
+	seen := map[string]bool{}
+	needsStaging := false
+	for _, info := range infos {
+		name := info.Name()
+		ext := filepath.Ext(name)
+		if info.IsDir() || (ext != ".json" && !isMarkdownFile(name)) {
+			continue
+		}
+		// markdown pages are staged as page json
+		stagedName := strings.TrimSuffix(name, ext) + ".json"
+		if seen[stagedName] {
+			return nil, fmt.Errorf("%s: a page json file of the same name exists",
+				filepath.Join(l.dir, name))
+		}
+		seen[stagedName] = true
+		data, changed, err := l.loadFile(filepath.Join(l.dir, name), transforms)
+		if err != nil {
+			return nil, err
+		}
+		needsStaging = needsStaging || changed
+		if data != nil {
+			staged[stagedName] = data
+		}
+	}
+
+	if !needsStaging {
+		return staticPersistence.ReadPagesFromDir(l.dir), nil
+	}
+	return l.loadStaged(staged)
+}
+
+// Reads a page json or markdown file and applies the
+// transforms, returns the possibly re-encoded content,
+// which is nil for pages which aren't published. The
+// fields of page json files the transforms change are
+// patched into the file, keeping all other fields.
+func (l *pageLoader) loadFile(file string, transforms []pageTransform) ([]byte, bool, error) {
+	if isMarkdownFile(file) {
+		doc, err := readMarkdownDoc(file)
+		if err != nil {
+			return nil, false, err
+		}
+		if published, err := l.publication.isPublished(doc); !published || err != nil {
+			return nil, true, err
+		}
+		if _, err := applyTransforms(doc, transforms); err != nil {
+			return nil, false, err
+		}
+		data, err := encodePageDoc(doc)
+		return data, true, err
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, false, err
+	}
+	doc, err := readPageDoc(file)
+	if err != nil {
+		// leave it to staticPersistence to deal with
+		log.Warn("pageLoader.loadFile() - ", err)
+		return data, false, nil
+	}
+	if published, err := l.publication.isPublished(doc); !published || err != nil {
+		return nil, true, err
+	}
+	before, err := encodePageDoc(doc)
+	if err != nil {
+		return nil, false, err
+	}
+	changed, err := applyTransforms(doc, transforms)
+	if err != nil || !changed {
+		return data, false, err
+	}
+	after, err := encodePageDoc(doc)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err = patchPageJson(data, before, after)
+	return data, true, err
+}
+
+// Applies the transforms to the document
+func applyTransforms(doc *pageDoc, transforms []pageTransform) (bool, error) {
+	changed := false
+	for _, transform := range transforms {
+		c, err := transform(doc)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || c
+	}
+	return changed, nil
+}
+
+func (l *pageLoader) loadStaged(staged map[string][]byte) ([]staticIntf.PageDto, error) {
+	stagingDir, err := ioutil.TempDir("", "static-pages")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	for name, data := range staged {
+		err := ioutil.WriteFile(filepath.Join(stagingDir, name), data, 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return staticPersistence.ReadPagesFromDir(stagingDir + string(os.PathSeparator)), nil
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticIntf"
+	log "github.com/sirupsen/logrus"
+)
+
+// The page types, whose rendering can be overridden
+// by a template named after them, like post.html
+const (
+	postTemplate      = "post"
+	naviTemplate      = "navi"
+	portfolioTemplate = "portfolio"
+	homeTemplate      = "home"
+	marginalTemplate  = "marginal"
+	narrativeTemplate = "narrative"
+
+	// holds the parts shared by the page templates
+	baseTemplateFile = "base.html"
+)
+
+var pageTemplateNames = []string{
+	postTemplate,
+	naviTemplate,
+	portfolioTemplate,
+	homeTemplate,
+	marginalTemplate,
+	narrativeTemplate}
+
+// Returns the page type of a page of a container of the
+// given variant, or "" for variants without page type
+func pageTemplateName(variant string, navi bool) string {
+	if navi {
+		return naviTemplate
+	}
+	switch variant {
+	case staticIntf.BLOG:
+		return postTemplate
+	case staticIntf.PORTFOLIO:
+		return portfolioTemplate
+	case staticIntf.HOME:
+		return homeTemplate
+	case staticIntf.MARGINALS, staticIntf.NARRATIVEMARGINALS:
+		return marginalTemplate
+	case staticIntf.NARRATIVES:
+		return narrativeTemplate
+	}
+	return ""
+}
+
+// Creates the page templates of a site from the html files
+// of the given dir. The parts of base.html the dir doesn't
+// define are taken from the built-in base.html.
+func NewPageTemplates(dir, targetDir, cssFileName string) (*pageTemplates, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("reading the templates: %v", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	return newPageTemplatesOf(files, targetDir, cssFileName)
+}
+
+// Creates the page templates of the given files, later
+// files replace earlier files and the built-in templates
+// of the same name
+func newPageTemplatesOf(files []string, targetDir, cssFileName string) (*pageTemplates, error) {
+	t := new(pageTemplates)
+	t.targetDir = targetDir
+	t.cssFileName = cssFileName
+
+	root, err := template.New(baseTemplateFile).Parse(defaultPageTemplates[baseTemplateFile])
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range pageTemplateNames {
+		if _, err := root.New(name + ".html").Parse(defaultPageTemplates[name+".html"]); err != nil {
+			return nil, err
+		}
+	}
+	if len(files) > 0 {
+		if root, err = root.ParseFiles(files...); err != nil {
+			return nil, err
+		}
+	}
+	t.root = root
+	return t, nil
+}
+
+// The pageTemplates render the pages of the page types,
+// with the built-in templates unless the site or its
+// theme has a template of the same name
+type pageTemplates struct {
+	root        *template.Template
+	targetDir   string
+	cssFileName string
+
+	// the path of the js bundle, if the site has js
+	js string
+}
+
+// The data the page templates are executed with
+type templateData struct {
+	Site     templateSite
+	Headline string
+	Page     *templatePage
+}
+
+type templateSite struct {
+	Domain   string
+	Css      string
+	Js       string
+	Main     []templateLink
+	Marginal []templateLink
+}
+
+type templateLink struct {
+	Title string
+	Url   string
+}
+
+type templatePage struct {
+	Title         string
+	Description   string
+	Category      string
+	PublishedTime string
+	Url           string
+	ImageUrl      string
+	ThumbnailUrl  string
+	Content       template.HTML
+
+	// the links of a post to the archives of its
+	// category and tags, if it has any
+	Archives *archiveLinks
+
+	// the pages listed on a navi page, and its
+	// neighbouring navi pages, if there are any
+	Pages []*templatePage
+	Prev  *templatePage
+	Next  *templatePage
+}
+
+// Renders the pages of the containers with the templates
+// of their page type, replacing the data of the file
+// containers the contexts of staticPresentation created
+func (t *pageTemplates) apply(
+	fcs []fs.FileContainer,
+	containers []staticIntf.PagesContainer,
+	site staticIntf.Site,
+	archives map[string]archiveLinks) {
+
+	pages := map[string]staticIntf.Page{}
+	headlines := map[string]string{}
+	names := map[string]string{}
+	for _, c := range containers {
+		for i, list := range [][]staticIntf.Page{c.Pages(), c.NaviPages()} {
+			name := pageTemplateName(c.Variant(), i == 1)
+			if name == "" {
+				continue
+			}
+			for _, p := range list {
+				file := filepath.Join(t.targetDir, p.PathFromDocRoot(), p.HtmlFilename())
+				pages[file] = p
+				headlines[file] = c.Headline()
+				names[file] = name
+			}
+		}
+	}
+	if len(pages) == 0 {
+		return
+	}
+
+	siteData := t.siteData(site)
+	linked := map[string]bool{}
+	for _, fc := range fcs {
+		file := filepath.Join(fc.GetPath(), fc.GetFilename())
+		p, ok := pages[file]
+		if !ok {
+			continue
+		}
+		page := newTemplatePage(p, true)
+		location := path.Join(p.PathFromDocRoot(), p.HtmlFilename())
+		if links, ok := archives[location]; ok {
+			page.Archives = &links
+		}
+		data, err := t.render(names[file], templateData{
+			Site:     siteData,
+			Headline: headlines[file],
+			Page:     page})
+		if err != nil {
+			log.Errorf("rendering %s with %s.html: %v", file, names[file], err)
+			continue
+		}
+		fc.SetData(data)
+		if page.Archives != nil {
+			linked[location] = true
+			if url := page.Archives.first(); !bytes.Contains(data, []byte(url)) {
+				log.Warnf("%s: %s.html doesn't link the archive %s of the post", file, names[file], url)
+			}
+		}
+	}
+	for location := range archives {
+		if !linked[location] && renders(containers, location) {
+			log.Warnf("%s: the post isn't rendered by a template, its archives aren't linked", location)
+		}
+	}
+}
+
+// Tells if one of the containers holds the page of the given location
+func renders(containers []staticIntf.PagesContainer, location string) bool {
+	for _, c := range containers {
+		for _, p := range c.Pages() {
+			if path.Join(p.PathFromDocRoot(), p.HtmlFilename()) == location {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *pageTemplates) render(name string, data templateData) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := t.root.ExecuteTemplate(buf, name+".html", data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *pageTemplates) siteData(site staticIntf.Site) templateSite {
+	return templateSite{
+		Domain:   site.Domain(),
+		Css:      "/" + t.cssFileName,
+		Js:       t.js,
+		Main:     templateLinks(site.Main()),
+		Marginal: templateLinks(site.Marginal())}
+}
+
+func templateLinks(locs []staticIntf.Location) []templateLink {
+	links := []templateLink{}
+	for _, l := range locs {
+		url := l.ExternalLink()
+		if url == "" {
+			url = l.Url()
+		}
+		links = append(links, templateLink{l.Title(), url})
+	}
+	return links
+}
+
+// Converts the page into template data, with the pages it
+// lists and its neighbours only if deep is set, to keep
+// the data of the listed pages flat
+func newTemplatePage(p staticIntf.Page, deep bool) *templatePage {
+	if p == nil {
+		return nil
+	}
+	tp := &templatePage{
+		Title:         p.Title(),
+		Description:   p.Description(),
+		Category:      p.Category(),
+		PublishedTime: p.PublishedTime(),
+		Url:           p.Url(),
+		ImageUrl:      p.ImageUrl(),
+		ThumbnailUrl:  p.ThumbnailUrl(),
+		Content:       template.HTML(p.Content())}
+	if !deep {
+		return tp
+	}
+	for _, np := range p.NavigatedPages() {
+		tp.Pages = append(tp.Pages, newTemplatePage(np, false))
+	}
+	if np, ok := p.(interface {
+		Prev() staticIntf.Page
+		Next() staticIntf.Page
+	}); ok {
+		tp.Prev = newTemplatePage(np.Prev(), false)
+		tp.Next = newTemplatePage(np.Next(), false)
+	}
+	return tp
+}
+
+// Writes the built-in templates into the given dir, as a
+// starting point for the templates of a site. Existing
+// files are kept, the names of the written files returned.
+func DumpPageTemplates(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name := range defaultPageTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	written := []string{}
+	for _, name := range names {
+		file := filepath.Join(dir, name)
+		if exists, _ := fs.PathExists(file); exists {
+			log.Warnf("keeping the existing %s", file)
+			continue
+		}
+		if err := ioutil.WriteFile(file, []byte(defaultPageTemplates[name]), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, file)
+	}
+	return written, nil
+}
+
+// The built-in templates the pages are rendered with, unless
+// the site or its theme replaces them. DumpPageTemplates
+// writes them as a starting point for own templates.
+var defaultPageTemplates = map[string]string{
+	baseTemplateFile: `{{define "head"}}<!doctype html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>{{.Page.Title}}</title>
+	{{if .Page.Description}}<meta name="description" content="{{.Page.Description}}">{{end}}
+	<link rel="canonical" href="{{.Page.Url}}">
+	{{with .Page.Prev}}<link rel="prev" href="{{.Url}}">{{end}}
+	{{with .Page.Next}}<link rel="next" href="{{.Url}}">{{end}}
+	<link rel="stylesheet" href="{{.Site.Css}}">
+	{{with .Site.Js}}<script src="{{.}}" defer></script>{{end}}
+</head>
+<body>
+{{end}}
+
+{{define "header"}}<header class="header">
+	<nav class="header__nav">
+		{{range .Site.Main}}<a href="{{.Url}}">{{.Title}}</a>
+		{{end}}
+	</nav>
+</header>
+{{end}}
+
+{{define "footer"}}<footer class="footer">
+	<nav class="footer__nav">
+		{{range .Site.Marginal}}<a href="{{.Url}}">{{.Title}}</a>
+		{{end}}
+	</nav>
+</footer>
+</body>
+</html>
+{{end}}
+
+{{define "teasers"}}<ul class="teasers">
+	{{range .}}<li class="teaser">
+		<a href="{{.Url}}">
+			{{if .ThumbnailUrl}}<img src="{{.ThumbnailUrl}}" alt="{{.Title}}">{{end}}
+			<span class="teaser__title">{{.Title}}</span>
+		</a>
+	</li>
+	{{end}}
+</ul>
+{{end}}
+`,
+	postTemplate + ".html": `{{template "head" .}}{{template "header" .}}
+<main class="post">
+	<h1>{{.Page.Title}}</h1>
+	{{if .Page.PublishedTime}}<time class="post__date">{{.Page.PublishedTime}}</time>{{end}}
+	{{if .Page.ImageUrl}}<img class="post__image" src="{{.Page.ImageUrl}}" alt="{{.Page.Title}}">{{end}}
+	<div class="post__content">{{.Page.Content}}</div>
+	{{with .Page.Archives}}<p class="tags">{{with .Category}}<a href="{{.Url}}" class="category">{{.Title}}</a>{{end}}{{range .Tags}} <a href="{{.Url}}" class="tag">{{.Title}}</a>{{end}}</p>{{end}}
+</main>
+{{template "footer" .}}`,
+	naviTemplate + ".html": `{{template "head" .}}{{template "header" .}}
+<main class="navi">
+	<h1>{{.Headline}}</h1>
+	{{template "teasers" .Page.Pages}}
+	<nav class="navi__pages">{{with .Page.Prev}}<a rel="prev" href="{{.Url}}">older</a>{{end}}{{with .Page.Next}}<a rel="next" href="{{.Url}}">newer</a>{{end}}</nav>
+</main>
+{{template "footer" .}}`,
+	portfolioTemplate + ".html": `{{template "head" .}}{{template "header" .}}
+<main class="portfolio">
+	<h1>{{.Page.Title}}</h1>
+	{{if .Page.ImageUrl}}<img class="portfolio__image" src="{{.Page.ImageUrl}}" alt="{{.Page.Title}}">{{end}}
+	<div class="portfolio__content">{{.Page.Content}}</div>
+</main>
+{{template "footer" .}}`,
+	homeTemplate + ".html": `{{template "head" .}}{{template "header" .}}
+<main class="home">
+	<div class="home__content">{{.Page.Content}}</div>
+</main>
+{{template "footer" .}}`,
+	marginalTemplate + ".html": `{{template "head" .}}{{template "header" .}}
+<main class="marginal">
+	<h1>{{.Page.Title}}</h1>
+	<div class="marginal__content">{{.Page.Content}}</div>
+</main>
+{{template "footer" .}}`,
+	narrativeTemplate + ".html": `{{template "head" .}}{{template "header" .}}
+<main class="narrative">
+	<h1>{{.Headline}}</h1>
+	<h2>{{.Page.Title}}</h2>
+	{{if .Page.ImageUrl}}<img class="narrative__image" src="{{.Page.ImageUrl}}" alt="{{.Page.Title}}">{{end}}
+	<div class="narrative__content">{{.Page.Content}}</div>
+</main>
+{{template "footer" .}}`,
+}
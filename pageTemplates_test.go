@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Builds a site of the tagged posts with the templates of
+// the given dir and returns its rendered files by path
+func givenTemplatedSite(t *testing.T, templates string) map[string]string {
+	s := givenRenderedSite(t, func(posts string) string {
+		return fmt.Sprintf(`[{
+			"domain": "drewing.de",
+			"deploy": {"targetDir": "deploy", "cssFileName": "styles.css"},
+			"src": [{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog"}],
+			"templates": %q
+		}]`, posts, templates)
+	})
+	return renderedFiles(s, nil)
+}
+
+func TestTemplatesOverridePageTypes(t *testing.T) {
+	tpls, _ := ioutil.TempDir("", "templates")
+	defer os.RemoveAll(tpls)
+	ioutil.WriteFile(filepath.Join(tpls, "post.html"), []byte(
+		`{{template "head" .}}<article>{{.Page.Title}}: {{.Page.Content}}</article>{{template "footer" .}}`), 0644)
+
+	files := givenTemplatedSite(t, tpls)
+
+	post := files["deploy/blog/post-0/index.html"]
+	if !strings.Contains(post, "<article>Post &amp; 0: <p>Some <em>text</em> of post 0") {
+		t.Error("Expected the post rendered by post.html, but got", post)
+	}
+	if !strings.Contains(post, `<footer class="footer">`) {
+		t.Error("Expected the footer of the built-in base.html, but got", post)
+	}
+	navi := files["deploy/blog/index.html"]
+	if navi == "" || strings.Contains(navi, "<article>") {
+		t.Error("Expected the built-in rendering of the navi page, but got", navi)
+	}
+}
+
+func TestTemplatesWithoutArchivesAreReported(t *testing.T) {
+	tpls, _ := ioutil.TempDir("", "templates")
+	defer os.RemoveAll(tpls)
+	ioutil.WriteFile(filepath.Join(tpls, "post.html"), []byte(`<article>{{.Page.Content}}</article>`), 0644)
+
+	buf := new(bytes.Buffer)
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+	givenTemplatedSite(t, tpls)
+
+	if !strings.Contains(buf.String(), "post.html doesn't link the archive https://drewing.de/blog/tags/go/") {
+		t.Error("Expected the missing archive links to be reported, but got", buf.String())
+	}
+}
+
+func TestDumpedTemplatesRenderAllPages(t *testing.T) {
+	tpls, _ := ioutil.TempDir("", "templates")
+	defer os.RemoveAll(tpls)
+	ioutil.WriteFile(filepath.Join(tpls, "home.html"), []byte("own"), 0644)
+
+	written, err := DumpPageTemplates(tpls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != len(defaultPageTemplates)-1 {
+		t.Error("Expected", len(defaultPageTemplates)-1, "files, but got", written)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(tpls, "home.html")); string(data) != "own" {
+		t.Error("Expected the existing home.html to be kept, but got", string(data))
+	}
+
+	files := givenTemplatedSite(t, tpls)
+
+	post := files["deploy/blog/post-2/index.html"]
+	if !strings.Contains(post, `<main class="post">`) || !strings.Contains(post, "<h1>Post &amp; 2</h1>") {
+		t.Error("Expected the post rendered by the dumped post.html, but got", post)
+	}
+	navi := files["deploy/blog/index.html"]
+	if !strings.Contains(navi, `<main class="navi">`) || !strings.Contains(navi, "<h1>Blog</h1>") {
+		t.Error("Expected the navi page rendered by the dumped navi.html, but got", navi)
+	}
+	if !strings.Contains(navi, `<link rel="stylesheet" href="/styles.`) {
+		t.Error("Expected a link to the css bundle, but got", navi)
+	}
+}
+
+func TestDumpedTemplatesRenderLikeTheBuiltIns(t *testing.T) {
+	tpls, _ := ioutil.TempDir("", "templates")
+	defer os.RemoveAll(tpls)
+	if _, err := DumpPageTemplates(tpls); err != nil {
+		t.Fatal(err)
+	}
+
+	builtIn := givenTemplatedSite(t, "")
+	dumped := givenTemplatedSite(t, tpls)
+
+	for _, name := range []string{"deploy/blog/post-1/index.html", "deploy/blog/index.html"} {
+		if builtIn[name] == "" || builtIn[name] != dumped[name] {
+			t.Error("Expected", name, "rendered alike by the dumped templates, but got", dumped[name],
+				"instead of", builtIn[name])
+		}
+	}
+}
+
+func TestPageTemplatesReportErrors(t *testing.T) {
+	if _, err := NewPageTemplates("does/not/exist", "deploy", "styles.css"); err == nil {
+		t.Error("Expected an error for a missing template dir")
+	}
+
+	tpls, _ := ioutil.TempDir("", "templates")
+	defer os.RemoveAll(tpls)
+	ioutil.WriteFile(filepath.Join(tpls, "post.html"), []byte(`{{.Page.Title`), 0644)
+	if _, err := NewPageTemplates(tpls, "deploy", "styles.css"); err == nil {
+		t.Error("Expected an error for a broken template")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticIntf"
+	"github.com/ingmardrewing/staticPersistence"
+	log "github.com/sirupsen/logrus"
+)
+
+// Creates a postAdder, which creates blog posts from
+// the images in the addPostDir of the given config
+func NewPostAdder(config staticPersistence.Config) *postAdder {
+	p := new(postAdder)
+	p.config = config
+	p.titles = titleForImage
+	p.now = time.Now
+	p.docRegex = regexp.MustCompile(`^doc(\d+)\.(json|md)$`)
+	return p
+}
+
+// Infers the title from the filename of the
+// image, asks the user if that isn't possible
+func titleForImage(filename string) (string, string) {
+	title, titlePlain := inferBlogTitleFromFilename(filename)
+	if title == "" || titlePlain == "" {
+		fmt.Println("Can't infer a title from", filename)
+		return askUserForTitle()
+	}
+	return title, titlePlain
+}
+
+type postAdder struct {
+	config   staticPersistence.Config
+	titles   func(filename string) (string, string)
+	now      func() time.Time
+	docRegex *regexp.Regexp
+}
+
+// Creates one post per image and returns the
+// paths of the written page json files
+func (p *postAdder) AddPosts() ([]string, error) {
+	if p.config.AddPostDir == "" {
+		return nil, errors.New("no addPostDir configured for " + p.config.Domain)
+	}
+	blogDir, subDir, err := p.blogSource()
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := ioutil.ReadDir(p.config.AddPostDir)
+	if err != nil {
+		return nil, err
+	}
+	written := []string{}
+	for _, info := range infos {
+		if info.IsDir() || !isSupportedImage(info.Name()) {
+			continue
+		}
+		file, err := p.addPost(info.Name(), blogDir, subDir)
+		if err != nil {
+			return written, err
+		}
+		written = append(written, file)
+	}
+	return written, nil
+}
+
+// Returns dir and subDir of the first blog source
+func (p *postAdder) blogSource() (string, string, error) {
+	for _, src := range p.config.Src {
+		if src.Type == staticIntf.BLOG {
+			return src.Dir, src.SubDir, nil
+		}
+	}
+	return "", "", errors.New("no blog source configured for " + p.config.Domain)
+}
+
+func (p *postAdder) addPost(filename, blogDir, subDir string) (string, error) {
+	log.Debug("postAdder.addPost() - ", filename)
+	imgFile := filepath.Join(p.config.AddPostDir, filename)
+	img, format, err := decodeImageFile(imgFile)
+	if err != nil {
+		return "", err
+	}
+
+	title, titlePlain := p.titles(filename)
+	now := p.now()
+	datePath := now.Format("2006/01/02")
+	assetDir := filepath.Join(blogDir, assetsDirName, filepath.FromSlash(datePath))
+	urlDir := "https://" + p.config.Domain + "/" + path.Join(subDir, datePath) + "/"
+
+	ext := filepath.Ext(filename)
+	suffix := p.uniqueSuffix(blogDir, path.Join(subDir, datePath, titlePlain),
+		filepath.Join(assetDir, strings.TrimSuffix(filename, ext)), ext)
+	titlePlain += suffix
+	name := strings.TrimSuffix(filename, ext) + suffix
+	urls := imageUrls{Title: title, MaxResolution: urlDir + name + ext}
+	for _, v := range variantUrls(&urls) {
+		variantName := fmt.Sprintf("%s-w%d%s", name, v.width, ext)
+		err := writeResizedImage(img, v.width, format, filepath.Join(assetDir, variantName))
+		if err != nil {
+			return "", err
+		}
+		*v.url = urlDir + variantName
+	}
+	thumb, err := thumbBase64(img)
+	if err != nil {
+		return "", err
+	}
+
+	doc := &pageDoc{
+		Version:         2,
+		Filename:        "index.html",
+		PathFromDocRoot: "/" + path.Join(subDir, datePath, titlePlain) + "/",
+		Category:        "blog post",
+		Tags:            docTags{},
+		CreateDate:      now.Format("2006-01-02"),
+		Title:           title,
+		TitlePlain:      titlePlain,
+		Excerpt:         p.config.DefaultMeta.BlogExcerpt,
+		Content: fmt.Sprintf(`<a href="%s"><img src="%s" width="800"></a>`,
+			urls.MaxResolution, urls.W800),
+		ThumbBase64: thumb,
+		ImagesUrls:  []imageUrls{urls}}
+
+	docFile, err := p.nextDocFile(blogDir)
+	if err != nil {
+		return "", err
+	}
+	if err := writePageDoc(doc, docFile); err != nil {
+		return "", err
+	}
+	return docFile, moveFile(imgFile, filepath.Join(assetDir, name+ext))
+}
+
+// Returns the suffix to append to the location of a post and
+// the names of its images, so that they don't overwrite those
+// of a post added before, e.g. of an equally titled image
+func (p *postAdder) uniqueSuffix(blogDir, location, image, ext string) string {
+	docs, _ := readPageDocs(blogDir)
+	used := map[string]bool{}
+	for _, doc := range docs {
+		used[strings.Trim(doc.PathFromDocRoot, "/")] = true
+	}
+	for n := 1; ; n++ {
+		suffix := ""
+		if n > 1 {
+			suffix = fmt.Sprintf("-%d", n)
+		}
+		exists, _ := fs.PathExists(image + suffix + ext)
+		if !used[location+suffix] && !exists {
+			if suffix != "" {
+				log.Info("postAdder.addPost() - ", location, " is taken, using ", location+suffix)
+			}
+			return suffix
+		}
+	}
+}
+
+// Returns the path for the next page json file,
+// numbered after the existing json and markdown pages
+func (p *postAdder) nextDocFile(dir string) (string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	numbers := []int{-1}
+	for _, info := range infos {
+		if m := p.docRegex.FindStringSubmatch(info.Name()); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	next := numbers[len(numbers)-1] + 1
+	return filepath.Join(dir, fmt.Sprintf("doc%05d.json", next)), nil
+}
+
+// Writes the page json to the given file
+func writePageDoc(doc *pageDoc, file string) error {
+	data, err := encodePageDoc(doc)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// Moves a file, copying it if it can't be
+// renamed, e.g. across file systems
+func moveFile(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+func givenPostAdder(t *testing.T) (*postAdder, string) {
+	dir, _ := ioutil.TempDir("", "add")
+	addDir := filepath.Join(dir, "add")
+	blogDir := filepath.Join(dir, "posts")
+	os.MkdirAll(addDir, 0755)
+	os.MkdirAll(blogDir, 0755)
+
+	data, _ := ioutil.ReadFile("testResources/src/add/TestImage.png")
+	ioutil.WriteFile(filepath.Join(addDir, "TestImage.png"), data, 0644)
+	ioutil.WriteFile(filepath.Join(blogDir, "doc00007.json"), []byte("{}"), 0644)
+	ioutil.WriteFile(filepath.Join(blogDir, "doc00008.md"), []byte("# Post"), 0644)
+
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(fmt.Sprintf(`[{
+		"domain": "drewing.de",
+		"addPostDir": %q,
+		"src": [{"dir": %q, "type": "blog", "subDir": "blog"}]
+	}]`, addDir, blogDir)), 0644)
+	config := staticPersistence.ReadConfig(dir, "config.json")[0]
+
+	p := NewPostAdder(config)
+	p.now = func() time.Time { return time.Date(2019, 1, 31, 12, 0, 0, 0, time.UTC) }
+	return p, dir
+}
+
+func TestPostAdderCreatesPost(t *testing.T) {
+	p, dir := givenPostAdder(t)
+	defer os.RemoveAll(dir)
+
+	files, err := p.AddPosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedFile := filepath.Join(dir, "posts", "doc00009.json")
+	if len(files) != 1 || files[0] != expectedFile {
+		t.Fatal("Expected", expectedFile, "to be written, but got", files)
+	}
+	doc, err := readPageDoc(expectedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != 2 || doc.Title != "Test Image" || doc.CreateDate != "2019-01-31" {
+		t.Error("Unexpected page json:", doc.Version, doc.Title, doc.CreateDate)
+	}
+	if doc.PathFromDocRoot != "/blog/2019/01/31/test-image/" {
+		t.Error("Unexpected path_from_doc_root:", doc.PathFromDocRoot)
+	}
+	expectedUrl := "https://drewing.de/blog/2019/01/31/TestImage-w390.png"
+	if doc.ImagesUrls[0].W390 != expectedUrl {
+		t.Error("Expected", expectedUrl, ", but got", doc.ImagesUrls[0].W390)
+	}
+	if doc.ThumbBase64 == "" {
+		t.Error("Expected thumbnail to be embedded")
+	}
+
+	assetDir := filepath.Join(dir, "posts", assetsDirName, "2019", "01", "31")
+	for name, width := range map[string]int{
+		"TestImage-w190.png": 190,
+		"TestImage-w390.png": 390,
+		"TestImage-w800.png": 800,
+		"TestImage.png":      1000} {
+		f, err := os.Open(filepath.Join(assetDir, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		cfg, _, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil || cfg.Width != width {
+			t.Error("Expected", name, "to be", width, "px wide, but got", cfg.Width, err)
+		}
+	}
+
+	inboxImage := filepath.Join(dir, "add", "TestImage.png")
+	if exists, _ := fs.PathExists(inboxImage); exists {
+		t.Error("Expected image to be moved out of the add post dir")
+	}
+}
+
+func TestPostAdderDoesntOverwritePostsOfTheSameTitle(t *testing.T) {
+	p, dir := givenPostAdder(t)
+	defer os.RemoveAll(dir)
+	p.titles = func(filename string) (string, string) { return "Same", "same" }
+
+	addDir := filepath.Join(dir, "add")
+	data, _ := ioutil.ReadFile(filepath.Join(addDir, "TestImage.png"))
+	ioutil.WriteFile(filepath.Join(addDir, "Other.png"), data, 0644)
+	if _, err := p.AddPosts(); err != nil {
+		t.Fatal(err)
+	}
+	// the same image is added again on the same day
+	ioutil.WriteFile(filepath.Join(addDir, "TestImage.png"), data, 0644)
+	files, err := p.AddPosts()
+	if err != nil || len(files) != 1 {
+		t.Fatal("Expected one more post, but got", files, err)
+	}
+
+	paths := []string{}
+	for _, n := range []string{"doc00009.json", "doc00010.json", "doc00011.json"} {
+		doc, err := readPageDoc(filepath.Join(dir, "posts", n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, doc.PathFromDocRoot)
+	}
+	expected := "[/blog/2019/01/31/same/ /blog/2019/01/31/same-2/ /blog/2019/01/31/same-3/]"
+	if fmt.Sprint(paths) != expected {
+		t.Error("Expected", expected, ", but got", paths)
+	}
+
+	assetDir := filepath.Join(dir, "posts", assetsDirName, "2019", "01", "31")
+	for _, name := range []string{
+		"Other.png", "Other-w800.png",
+		"TestImage-2.png", "TestImage-2-w800.png",
+		"TestImage-3.png", "TestImage-3-w800.png"} {
+		if exists, _ := fs.PathExists(filepath.Join(assetDir, name)); !exists {
+			t.Error("Expected", name, "to be written")
+		}
+	}
+	doc, _ := readPageDoc(files[0])
+	expectedUrl := "https://drewing.de/blog/2019/01/31/TestImage-3-w390.png"
+	if doc.ImagesUrls[0].W390 != expectedUrl {
+		t.Error("Expected", expectedUrl, ", but got", doc.ImagesUrls[0].W390)
+	}
+}
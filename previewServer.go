@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ingmardrewing/staticPersistence"
+	log "github.com/sirupsen/logrus"
+)
+
+// Creates a new previewServer, which serves the
+// rendered files of the site defined by the given
+// config on the given local address
+func NewPreviewServer(config staticPersistence.Config, addr string) *previewServer {
+	p := new(previewServer)
+	p.dir = config.Deploy.TargetDir
+	p.addr = addr
+	// https, http and protocol relative urls, with or without www,
+	// unless the domain is only the prefix of another host name
+	domain := regexp.QuoteMeta(strings.TrimPrefix(config.Domain, "www."))
+	p.domainRegex = regexp.MustCompile(`(?:https?:)?//(?:www\.)?` + domain + `([^\w.-]|$)`)
+	p.localUrl = "http://" + addr
+	return p
+}
+
+// The previewServer rewrites absolute urls pointing
+// to the domain of the site to the local origin, so
+// the site can be navigated offline
+type previewServer struct {
+	dir         string
+	addr        string
+	domainRegex *regexp.Regexp
+	localUrl    string
+}
+
+// Starts serving, blocks until the server fails
+func (p *previewServer) ListenAndServe() {
+	log.Fatal(http.ListenAndServe(p.addr, p))
+}
+
+func (p *previewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	urlPath := path.Clean("/" + r.URL.Path)
+	filePath := filepath.Join(p.dir, filepath.FromSlash(urlPath))
+
+	info, err := os.Stat(filePath)
+	if err == nil && info.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, urlPath+"/", http.StatusMovedPermanently)
+			return
+		}
+		filePath = filepath.Join(filePath, "index.html")
+		info, err = os.Stat(filePath)
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		log.Error("previewServer.ServeHTTP() - ", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p.rewritable(filePath) {
+		data = p.rewrite(data)
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), bytes.NewReader(data))
+}
+
+func (p *previewServer) rewritable(filePath string) bool {
+	switch filepath.Ext(filePath) {
+	case ".html", ".css", ".js", ".xml", ".json":
+		return true
+	}
+	return false
+}
+
+func (p *previewServer) rewrite(data []byte) []byte {
+	return p.domainRegex.ReplaceAll(data, []byte(p.localUrl+"$1"))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+func TestPreviewServerRewritesDomainUrls(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "preview")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(path.Join(dir, "index.html"),
+		[]byte(`<a href="https://drewing.de/blog/">blog</a>`), 0644)
+
+	config := staticPersistence.Config{}
+	config.Domain = "drewing.de"
+	config.Deploy.TargetDir = dir
+	p := NewPreviewServer(config, "localhost:8080")
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	expected := `<a href="http://localhost:8080/blog/">blog</a>`
+	actual := rec.Body.String()
+	if actual != expected {
+		t.Error("Expected", expected, ", but got", actual)
+	}
+}
+
+func TestPreviewServerRewritesAllFormsOfDomainUrls(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "preview")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(path.Join(dir, "index.html"), []byte(`<a href="https://drewing.de/a/">a</a>
+<a href="http://drewing.de/b/">b</a>
+<a href="http://www.drewing.de/c/">c</a>
+<img src="//drewing.de/d.png">
+<a href="https://drewing.de">home</a>
+<a href="https://drewing.de.example.com/">other</a>`), 0644)
+
+	config := staticPersistence.Config{}
+	config.Domain = "drewing.de"
+	config.Deploy.TargetDir = dir
+	p := NewPreviewServer(config, "localhost:8080")
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	expected := `<a href="http://localhost:8080/a/">a</a>
+<a href="http://localhost:8080/b/">b</a>
+<a href="http://localhost:8080/c/">c</a>
+<img src="http://localhost:8080/d.png">
+<a href="http://localhost:8080">home</a>
+<a href="https://drewing.de.example.com/">other</a>`
+	actual := rec.Body.String()
+	if actual != expected {
+		t.Error("Expected", expected, ", but got", actual)
+	}
+}
+
+func TestPreviewServerNotFound(t *testing.T) {
+	config := staticPersistence.Config{}
+	config.Deploy.TargetDir = os.TempDir()
+	p := NewPreviewServer(config, "localhost:8080")
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/does/not/exist.html", nil))
+
+	if rec.Code != 404 || !strings.Contains(rec.Body.String(), "not found") {
+		t.Error("Expected 404, but got", rec.Code)
+	}
+}
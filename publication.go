@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Formats accepted for the publish_date of a page,
+// dates without time are published at midnight
+var publishDateFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02"}
+
+// Parses the publish_date of a page in local time
+func parsePublishDate(date string) (time.Time, error) {
+	for _, format := range publishDateFormats {
+		if t, err := time.ParseInLocation(format, date, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("publish_date %q is neither YYYY-MM-DD nor YYYY-MM-DDThh:mm:ss", date)
+}
+
+// Creates a publication deciding at the given time
+// which pages are published. If drafts is true,
+// drafts and scheduled pages are published as well.
+func NewPublication(now time.Time, drafts bool) *publication {
+	p := new(publication)
+	p.now = now
+	p.drafts = drafts
+	p.scheduled = map[string]bool{}
+	p.published = map[string]bool{}
+	return p
+}
+
+// The publication holds back drafts and pages whose
+// publish_date lies in the future. It remembers the
+// pages it has seen, to tell which scheduled pages
+// became due since an earlier build.
+type publication struct {
+	now       time.Time
+	drafts    bool
+	mu        sync.Mutex
+	scheduled map[string]bool
+	published map[string]bool
+}
+
+// Tells if the page is published
+func (p *publication) isPublished(doc *pageDoc) (bool, error) {
+	location := path.Join(doc.PathFromDocRoot, doc.Filename)
+	scheduled := false
+	if doc.PublishDate != "" {
+		date, err := parsePublishDate(doc.PublishDate)
+		if err != nil {
+			return false, fmt.Errorf("%s: %v", doc.file, err)
+		}
+		scheduled = date.After(p.now)
+	}
+	published := !doc.Draft && !scheduled
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if scheduled && !doc.Draft {
+		p.scheduled[location] = true
+	}
+	if published {
+		p.published[location] = true
+	}
+	return published || p.drafts, nil
+}
+
+// Returns the locations of the pages held
+// back because of their publish_date, sorted
+func (p *publication) Scheduled() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return sortedKeys(p.scheduled)
+}
+
+// Returns the pages which were scheduled at an
+// earlier build and are published now, sorted
+func (p *publication) Due(earlierScheduled []string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	due := []string{}
+	for _, location := range earlierScheduled {
+		if p.published[location] {
+			due = append(due, location)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
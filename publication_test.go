@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func givenPublication(drafts bool) *publication {
+	return NewPublication(time.Date(2019, 1, 31, 12, 0, 0, 0, time.Local), drafts)
+}
+
+func TestPublicationIsPublished(t *testing.T) {
+	cases := []struct {
+		doc               pageDoc
+		expected, drafted bool
+	}{
+		{pageDoc{}, true, true},
+		{pageDoc{Draft: true}, false, true},
+		{pageDoc{PublishDate: "2019-01-31"}, true, true},
+		{pageDoc{PublishDate: "2019-01-31T12:30:00"}, false, true},
+		{pageDoc{PublishDate: "2019-02-01T00:00:00+01:00"}, false, true},
+		{pageDoc{PublishDate: "2019-01-30", Draft: true}, false, true}}
+
+	for _, c := range cases {
+		published, err := givenPublication(false).isPublished(&c.doc)
+		if err != nil || published != c.expected {
+			t.Error("Expected", c.expected, "for", c.doc.PublishDate, c.doc.Draft, ", but got", published, err)
+		}
+		published, err = givenPublication(true).isPublished(&c.doc)
+		if err != nil || published != c.drafted {
+			t.Error("Expected", c.drafted, "with drafts for", c.doc.PublishDate, c.doc.Draft, ", but got", published, err)
+		}
+	}
+}
+
+func TestPublicationRejectsInvalidDate(t *testing.T) {
+	_, err := givenPublication(false).isPublished(&pageDoc{PublishDate: "31.01.2019"})
+	if err == nil {
+		t.Error("Expected an error for an invalid publish_date")
+	}
+}
+
+func TestPublicationDue(t *testing.T) {
+	earlier := NewPublication(time.Date(2019, 1, 30, 0, 0, 0, 0, time.Local), false)
+	later := givenPublication(false)
+	docs := []pageDoc{
+		{PathFromDocRoot: "/blog/due/", Filename: "index.html", PublishDate: "2019-01-31"},
+		{PathFromDocRoot: "/blog/later/", Filename: "index.html", PublishDate: "2019-02-28"},
+		{PathFromDocRoot: "/blog/draft/", Filename: "index.html", PublishDate: "2019-01-31", Draft: true}}
+	for i := range docs {
+		earlier.isPublished(&docs[i])
+		later.isPublished(&docs[i])
+	}
+
+	expected := []string{"/blog/due/index.html", "/blog/later/index.html"}
+	if !reflect.DeepEqual(earlier.Scheduled(), expected) {
+		t.Error("Expected", expected, ", but got", earlier.Scheduled())
+	}
+	expected = []string{"/blog/due/index.html"}
+	if due := later.Due(earlier.Scheduled()); !reflect.DeepEqual(due, expected) {
+		t.Error("Expected", expected, ", but got", due)
+	}
+}
+
+func TestPageLoaderLeavesOutDrafts(t *testing.T) {
+	dir := givenPageFiles(t, map[string]string{
+		"doc00000.json": jsonPage,
+		"doc00001.md":   "---\npath: /blog/draft/\ndraft: true\n---\ntext\n"})
+	defer os.RemoveAll(dir)
+
+	l := NewPageLoader(dir)
+	if dtos := l.Load(); len(dtos) != 1 || l.Err() != nil {
+		t.Error("Expected the draft to be left out, but got", len(dtos), l.Err())
+	}
+
+	l.publication = givenPublication(true)
+	if dtos := l.Load(); len(dtos) != 2 || l.Err() != nil {
+		t.Error("Expected the draft to be included, but got", len(dtos), l.Err())
+	}
+}
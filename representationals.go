@@ -0,0 +1,126 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"path"
+	"sort"
+
+	"github.com/ingmardrewing/staticIntf"
+)
+
+// Strategies picking the representational pages of a source
+const (
+	// the newest pages
+	pickLatest = "latest"
+
+	// the newest pages flagged as featured
+	pickFeatured = "featured"
+
+	// random pages, the same ones as long
+	// as the pages of the source don't change
+	pickRandom = "random"
+
+	// the newest pages with the configured tag
+	pickTag = "tag"
+)
+
+// Creates a representationalPicker with the given settings,
+// defaultCount is used if they don't set a count. A negative
+// count picks all pages. Without settings, pages are only
+// picked from sources with more than defaultCount pages.
+func NewRepresentationalPicker(settings representationalSettings, defaultCount int) *representationalPicker {
+	r := new(representationalPicker)
+	r.settings = settings
+	if settings == (representationalSettings{}) && defaultCount > 0 {
+		r.minPages = defaultCount + 1
+	}
+	if r.settings.Count == 0 {
+		r.settings.Count = defaultCount
+	}
+	if r.settings.Strategy == "" {
+		r.settings.Strategy = pickLatest
+	}
+	r.featured = map[string]bool{}
+	r.tagged = map[string]bool{}
+	return r
+}
+
+// The representationalPicker picks the pages of a
+// source representing it, e.g. as teasers on the home
+// page. The featured flag and the tags of the pages
+// are collected while the page documents are loaded.
+type representationalPicker struct {
+	settings representationalSettings
+	minPages int
+	featured map[string]bool
+	tagged   map[string]bool
+}
+
+// Records the featured flag and the tags of
+// a page, to be used as pageTransform
+func (r *representationalPicker) collectPost(doc *pageDoc) (bool, error) {
+	location := path.Join(doc.PathFromDocRoot, doc.Filename)
+	if doc.Featured {
+		r.featured[location] = true
+	}
+	for _, tag := range doc.Tags {
+		if slugify(tag) == slugify(r.settings.Tag) {
+			r.tagged[location] = true
+		}
+	}
+	return false, nil
+}
+
+// Picks the representational pages from the given
+// pages of the source, keeping their order
+func (r *representationalPicker) Pick(pages []staticIntf.Page) []staticIntf.Page {
+	if len(pages) < r.minPages {
+		return []staticIntf.Page{}
+	}
+	candidates := []int{}
+	for i, p := range pages {
+		location := path.Join(p.PathFromDocRoot(), p.HtmlFilename())
+		switch r.settings.Strategy {
+		case pickFeatured:
+			if !r.featured[location] {
+				continue
+			}
+		case pickTag:
+			if !r.tagged[location] {
+				continue
+			}
+		}
+		candidates = append(candidates, i)
+	}
+
+	count := r.settings.Count
+	if count < 0 || count > len(candidates) {
+		count = len(candidates)
+	}
+	if r.settings.Strategy == pickRandom {
+		rand.New(rand.NewSource(pagesSeed(pages))).Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+		candidates = candidates[:count]
+		sort.Ints(candidates)
+	} else {
+		candidates = candidates[len(candidates)-count:]
+	}
+
+	picked := []staticIntf.Page{}
+	for _, i := range candidates {
+		picked = append(picked, pages[i])
+	}
+	return picked
+}
+
+// Derives a seed from the locations of the pages, so
+// random picks only change if the pages change
+func pagesSeed(pages []staticIntf.Page) int64 {
+	h := fnv.New64a()
+	for _, p := range pages {
+		h.Write([]byte(path.Join(p.PathFromDocRoot(), p.HtmlFilename())))
+	}
+	return int64(h.Sum64())
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func givenPicker(settings representationalSettings, posts int) *representationalPicker {
+	picker := NewRepresentationalPicker(settings, 4)
+	for i := 0; i < posts; i++ {
+		picker.collectPost(&pageDoc{
+			PathFromDocRoot: fmt.Sprintf("/blog/post-%d/", i),
+			Filename:        "index.html",
+			Featured:        i%3 == 0,
+			Tags:            docTags{fmt.Sprintf("tag%d", i%2)}})
+	}
+	return picker
+}
+
+func pickedTitles(picker *representationalPicker, posts int) string {
+	titles := []string{}
+	for _, p := range picker.Pick(givenPostsContainer(posts).Pages()) {
+		titles = append(titles, p.Title())
+	}
+	return fmt.Sprint(titles)
+}
+
+func TestRepresentationalStrategies(t *testing.T) {
+	cases := []struct {
+		settings representationalSettings
+		expected []string
+	}{
+		{representationalSettings{}, []string{"Post 6", "Post 7", "Post 8", "Post 9"}},
+		{representationalSettings{Count: 2}, []string{"Post 8", "Post 9"}},
+		{representationalSettings{Count: -1, Strategy: pickFeatured}, []string{"Post 0", "Post 3", "Post 6", "Post 9"}},
+		{representationalSettings{Count: 2, Strategy: pickFeatured}, []string{"Post 6", "Post 9"}},
+		{representationalSettings{Count: 3, Strategy: pickTag, Tag: "Tag1"}, []string{"Post 5", "Post 7", "Post 9"}}}
+
+	for _, c := range cases {
+		picker := givenPicker(c.settings, 10)
+		if actual := pickedTitles(picker, 10); actual != fmt.Sprint(c.expected) {
+			t.Error("Expected", c.expected, "for", c.settings, ", but got", actual)
+		}
+	}
+}
+
+func TestRandomRepresentationalsAreStable(t *testing.T) {
+	settings := representationalSettings{Count: 3, Strategy: pickRandom}
+	first := givenPicker(settings, 10)
+	second := givenPicker(settings, 10)
+
+	picked := pickedTitles(first, 10)
+	if picked != pickedTitles(second, 10) {
+		t.Error("Expected the same random pages for the same posts")
+	}
+	if len(first.Pick(givenPostsContainer(10).Pages())) != 3 {
+		t.Error("Expected 3 random pages, but got", picked)
+	}
+}
+
+func TestRepresentationalsOfSmallSources(t *testing.T) {
+	if picked := pickedTitles(givenPicker(representationalSettings{}, 4), 4); picked != "[]" {
+		t.Error("Expected no pages of a source with 4 pages by default, but got", picked)
+	}
+	if picked := pickedTitles(givenPicker(representationalSettings{}, 5), 5); picked != "[Post 1 Post 2 Post 3 Post 4]" {
+		t.Error("Expected the latest 4 pages of a source with 5 pages, but got", picked)
+	}
+	settings := representationalSettings{Strategy: pickLatest}
+	if picked := pickedTitles(givenPicker(settings, 3), 3); picked != "[Post 0 Post 1 Post 2]" {
+		t.Error("Expected all pages of a small source with settings, but got", picked)
+	}
+}
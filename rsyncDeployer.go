@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum duration of the transfer of all files
+const rsyncTimeout = 10 * time.Minute
+
+var (
+	rsyncVersionRegex = regexp.MustCompile(`version v?(\d+)\.(\d+)\.(\d+)`)
+	minRsyncVersion   = [3]int{3, 2, 3}
+)
+
+// Creates a deployer uploading via rsync over ssh
+func NewRsyncDeployer(settings uploadSettings) *rsyncDeployer {
+	r := new(rsyncDeployer)
+	r.settings = settings
+	return r
+}
+
+type rsyncDeployer struct {
+	settings uploadSettings
+}
+
+// Checks for rsync supporting --mkpath, to fail before
+// the deployment instead of with the first upload
+func (r *rsyncDeployer) Connect() error {
+	out, err := exec.Command("rsync", "--version").Output()
+	if err != nil {
+		return fmt.Errorf("running rsync --version: %v", err)
+	}
+	return checkRsyncVersion(string(out))
+}
+
+// The arguments of rsync keep the remote shell from
+// splitting the remote path at spaces or interpreting it
+func (r *rsyncDeployer) Upload(localPath, remotePath string) error {
+	return r.run("rsync",
+		"--mkpath", "--protect-args", "--times", "-e", r.shell(),
+		localPath, r.remote()+":"+remotePath)
+}
+
+// Uploads the given files of the local dir in a single
+// rsync call, returning the files it transferred
+func (r *rsyncDeployer) UploadAll(localDir, remoteDir string, rels []string) ([]string, error) {
+	list, err := ioutil.TempFile("", "rsync-files")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(list.Name())
+	_, err = list.WriteString(strings.Join(rels, "\n") + "\n")
+	if cerr := list.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCommand("rsync", r.uploadAllArgs(list.Name(), localDir, remoteDir)...)
+	c.setTimeout(rsyncTimeout)
+	err = c.run()
+	return transferredFiles(c.output(), rels), err
+}
+
+func (r *rsyncDeployer) uploadAllArgs(listFile, localDir, remoteDir string) []string {
+	return []string{
+		"--mkpath", "--protect-args", "--times", "--out-format=%n",
+		"--files-from=" + listFile, "-e", r.shell(),
+		strings.TrimSuffix(localDir, "/") + "/",
+		r.remote() + ":" + strings.TrimSuffix(remoteDir, "/") + "/"}
+}
+
+// Returns an error unless the output of rsync --version
+// names version 3.2.3 or later, the first with --mkpath
+func checkRsyncVersion(output string) error {
+	m := rsyncVersionRegex.FindStringSubmatch(output)
+	if m == nil {
+		return fmt.Errorf("rsync 3.2.3 or later is needed, but its version is unknown")
+	}
+	version := [3]int{}
+	for i := range version {
+		version[i], _ = strconv.Atoi(m[i+1])
+	}
+	for i, min := range minRsyncVersion {
+		if version[i] != min {
+			if version[i] < min {
+				return fmt.Errorf("rsync 3.2.3 or later is needed for --mkpath, but %s is installed",
+					strings.Join(m[1:], "."))
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// Returns the given files rsync reported as transferred
+func transferredFiles(output string, rels []string) []string {
+	reported := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		reported[strings.TrimSpace(line)] = true
+	}
+	transferred := []string{}
+	for _, rel := range rels {
+		if reported[rel] {
+			transferred = append(transferred, rel)
+		}
+	}
+	return transferred
+}
+
+func (r *rsyncDeployer) Remove(remotePath string) error {
+	args := append(r.sshCommand()[1:], r.remote(), "rm", "-f", "--", shellQuote(remotePath))
+	return r.run("ssh", args...)
+}
+
+func (r *rsyncDeployer) Close() error { return nil }
+
+func (r *rsyncDeployer) remote() string {
+	if r.settings.User == "" {
+		return r.settings.Host
+	}
+	return r.settings.User + "@" + r.settings.Host
+}
+
+// Returns the ssh command as rsync expects it, quoting
+// its arguments, like a key file path with spaces
+func (r *rsyncDeployer) shell() string {
+	quoted := []string{}
+	for _, arg := range r.sshCommand() {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+func (r *rsyncDeployer) sshCommand() []string {
+	cmd := []string{"ssh", "-o", "BatchMode=yes"}
+	if r.settings.Port != 0 {
+		cmd = append(cmd, "-p", strconv.Itoa(r.settings.Port))
+	}
+	if r.settings.KeyFile != "" {
+		cmd = append(cmd, "-i", r.settings.KeyFile)
+	}
+	if r.settings.KnownHostsFile != "" {
+		cmd = append(cmd, "-o", "UserKnownHostsFile="+r.settings.KnownHostsFile)
+	}
+	return cmd
+}
+
+func (r *rsyncDeployer) run(name string, args ...string) error {
+	c := newCommand(name, args...)
+	c.setTimeout(rsyncTimeout)
+	return c.run()
+}
+
+// Quotes the given string for a posix shell, as the
+// remote command of ssh is run by the shell of the host
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/ingmardrewing/staticIntf"
+)
+
+const (
+	searchIndexFilename = "search.json"
+	defaultSearchPath   = "search"
+
+	// length of the excerpts derived from the
+	// content of pages without excerpt
+	searchExcerptLength = 200
+)
+
+// Creates an empty searchIndex
+func NewSearchIndex() *searchIndex {
+	i := new(searchIndex)
+	i.entries = map[string]*searchDocEntry{}
+	return i
+}
+
+// The searchIndex lists the title, excerpt, tags, url and
+// the words of the content of every page of a site, to be
+// searched by the browser. Tags and excerpts of the pages
+// are collected while the page documents are loaded.
+type searchIndex struct {
+	mu      sync.Mutex
+	entries map[string]*searchDocEntry
+}
+
+// The data of a page document used by the index
+type searchDocEntry struct {
+	tags    []string
+	excerpt string
+}
+
+// One page within the index as written to search.json
+type searchEntry struct {
+	Title   string   `json:"title"`
+	Url     string   `json:"url"`
+	Excerpt string   `json:"excerpt,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Words   string   `json:"words"`
+}
+
+// Records the tags and the excerpt of a
+// page, to be used as pageTransform
+func (i *searchIndex) collectPost(doc *pageDoc) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.entries[path.Join(doc.PathFromDocRoot, doc.Filename)] = &searchDocEntry{
+		tags:    append([]string{}, doc.Tags...),
+		excerpt: doc.Excerpt}
+	return false, nil
+}
+
+// Creates the entries of all pages of the given
+// containers, leaving out navi pages
+func (i *searchIndex) Entries(containers []staticIntf.PagesContainer) []searchEntry {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	entries := []searchEntry{}
+	seen := map[string]bool{}
+	for _, c := range containers {
+		for _, p := range c.Pages() {
+			if seen[p.Url()] {
+				continue
+			}
+			seen[p.Url()] = true
+
+			doc := i.entries[path.Join(p.PathFromDocRoot(), p.HtmlFilename())]
+			if doc == nil {
+				doc = new(searchDocEntry)
+			}
+			text := plainText(p.Content())
+			excerpt := doc.excerpt
+			if excerpt == "" {
+				excerpt = p.Description()
+			}
+			if excerpt == "" {
+				excerpt = truncateText(text, searchExcerptLength)
+			} else {
+				excerpt = plainText(excerpt)
+			}
+			entries = append(entries, searchEntry{
+				Title:   plainText(p.Title()),
+				Url:     p.Url(),
+				Excerpt: excerpt,
+				Tags:    doc.tags,
+				Words:   searchWords(p.Title() + " " + text)})
+		}
+	}
+	return entries
+}
+
+// Returns the distinct lower case words of the text,
+// separated by blanks, in the order of their first use
+func searchWords(text string) string {
+	words := []string{}
+	seen := map[string]bool{}
+	for _, w := range strings.Split(slugRegex.ReplaceAllString(strings.ToLower(text), " "), " ") {
+		if len([]rune(w)) < 2 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		words = append(words, w)
+	}
+	return strings.Join(words, " ")
+}
+
+// Encodes the entries without indentation, to
+// keep the index small
+func encodeSearchIndex(entries []searchEntry) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(entries)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"path"
+	"path/filepath"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticIntf"
+	log "github.com/sirupsen/logrus"
+)
+
+// Creates the context rendering the search index of
+// the given site and the search page using it
+func NewSearchContext(
+	site staticIntf.Site,
+	index *searchIndex,
+	targetDir, cssFileName, jsFileName string,
+	settings searchSettings) *searchContext {
+
+	c := new(searchContext)
+	c.site = site
+	c.index = index
+	c.targetDir = targetDir
+	c.cssFileName = cssFileName
+	c.jsFileName = jsFileName
+	c.settings = settings
+	if c.settings.Path == "" {
+		c.settings.Path = defaultSearchPath
+	}
+	return c
+}
+
+// The searchContext renders search.json, listing all
+// pages of the site, and the search page, which loads
+// it and searches it in the browser
+type searchContext struct {
+	site        staticIntf.Site
+	index       *searchIndex
+	targetDir   string
+	cssFileName string
+	jsFileName  string
+	settings    searchSettings
+}
+
+func (c *searchContext) GetComponents() []staticIntf.Component {
+	return []staticIntf.Component{new(searchComponent)}
+}
+
+func (c *searchContext) RenderPages() []fs.FileContainer {
+	fcs := []fs.FileContainer{}
+
+	data, err := encodeSearchIndex(c.index.Entries(c.site.Containers()))
+	if err != nil {
+		log.Errorf("rendering %s: %v", searchIndexFilename, err)
+		return fcs
+	}
+	indexFc := fs.NewFileContainer()
+	indexFc.SetData(data)
+	indexFc.SetPath(c.targetDir)
+	indexFc.SetFilename(searchIndexFilename)
+	fcs = append(fcs, indexFc)
+
+	page := new(bytes.Buffer)
+	err = searchPageTemplate.Execute(page, map[string]interface{}{
+		"Title":    "Search – " + c.site.Domain(),
+		"Css":      "/" + c.cssFileName,
+		"IndexUrl": "/" + searchIndexFilename,
+		"Js":       "/" + c.jsFileName})
+	if err != nil {
+		log.Errorf("rendering the search page: %v", err)
+		return fcs
+	}
+	pageFc := fs.NewFileContainer()
+	pageFc.SetData(page.Bytes())
+	pageFc.SetPath(filepath.Join(c.targetDir, path.Clean("/"+c.settings.Path)))
+	pageFc.SetFilename("index.html")
+	return append(fcs, pageFc)
+}
+
+var searchPageTemplate = template.Must(template.New("search").Parse(`<!doctype html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<meta name="robots" content="noindex">
+	<title>{{.Title}}</title>
+	<link rel="stylesheet" href="{{.Css}}">
+</head>
+<body>
+	<main class="search">
+		<form class="search__form" role="search">
+			<input class="search__input" type="search" name="q" placeholder="Search" autofocus>
+		</form>
+		<p class="search__status"></p>
+		<ol class="search__results" data-index="{{.IndexUrl}}"></ol>
+	</main>
+	<script src="{{.Js}}" defer></script>
+</body>
+</html>
+`))
+
+// The styles and the script of the search page, which
+// become part of the css and js bundles of the site
+type searchComponent struct{}
+
+func (s *searchComponent) GetCss() string {
+	return `.search{max-width:40em;margin:2em auto;padding:0 1em}
+.search__input{width:100%;font-size:1.2em;padding:.3em}
+.search__results{padding-left:1.2em}
+.search__results li{margin:1em 0}
+.search__excerpt{margin:.2em 0}
+`
+}
+
+func (s *searchComponent) GetJs() string {
+	return `(function () {
+	var results = document.querySelector(".search__results");
+	if (!results) { return; }
+	var input = document.querySelector(".search__input");
+	var status = document.querySelector(".search__status");
+	var pages = [];
+
+	function score(page, terms) {
+		var total = 0;
+		var title = page.title.toLowerCase();
+		var tags = (page.tags || []).join(" ").toLowerCase();
+		var words = " " + page.words;
+		for (var i = 0; i < terms.length; i++) {
+			var s = 0;
+			if (title.indexOf(terms[i]) >= 0) { s += 3; }
+			if (tags.indexOf(terms[i]) >= 0) { s += 2; }
+			if (words.indexOf(" " + terms[i]) >= 0) { s += 1; }
+			if (s === 0) { return 0; }
+			total += s;
+		}
+		return total;
+	}
+
+	function search() {
+		var query = input.value.toLowerCase().trim();
+		var terms = query.split(/[^\p{L}\p{N}]+/u).filter(function (t) { return t.length > 0; });
+		results.innerHTML = "";
+		if (terms.length === 0) { status.textContent = ""; return; }
+		var hits = [];
+		pages.forEach(function (page) {
+			var s = score(page, terms);
+			if (s > 0) { hits.push({page: page, score: s}); }
+		});
+		hits.sort(function (a, b) { return b.score - a.score; });
+		status.textContent = hits.length + (hits.length === 1 ? " result" : " results");
+		hits.forEach(function (hit) {
+			var li = document.createElement("li");
+			var a = document.createElement("a");
+			a.href = hit.page.url;
+			a.textContent = hit.page.title;
+			var p = document.createElement("p");
+			p.className = "search__excerpt";
+			p.textContent = hit.page.excerpt || "";
+			li.appendChild(a);
+			li.appendChild(p);
+			results.appendChild(li);
+		});
+	}
+
+	input.value = new URLSearchParams(location.search).get("q") || "";
+	input.addEventListener("input", search);
+	fetch(results.getAttribute("data-index"))
+		.then(function (r) { return r.json(); })
+		.then(function (index) { pages = index; search(); });
+})();
+`
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/staticIntf"
+)
+
+// Builds a site of the tagged posts and the test resource
+// pages and returns its rendered search files by path
+func givenSearch(t *testing.T, search string) map[string]string {
+	s := givenRenderedSite(t, func(posts string) string {
+		return fmt.Sprintf(`[{
+			"domain": "drewing.de",
+			"deploy": {"targetDir": "deploy", "cssFileName": "styles.css"},
+			"src": [
+				{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog"},
+				{"dir": %q, "type": "main", "subDir": ""}
+			],
+			"search": %s
+		}]`, posts, absPath("testResources/src/pages/"), search)
+	})
+	return renderedFiles(s, func(ctx staticIntf.Context) bool {
+		_, ok := ctx.(*searchContext)
+		return ok
+	})
+}
+
+func TestSearchIndexListsAllPages(t *testing.T) {
+	files := givenSearch(t, `{}`)
+
+	entries := []searchEntry{}
+	if err := json.Unmarshal([]byte(files["deploy/search.json"]), &entries); err != nil {
+		t.Fatal(err)
+	}
+	byUrl := map[string]searchEntry{}
+	for _, e := range entries {
+		byUrl[e.Url] = e
+	}
+
+	post, ok := byUrl["https://drewing.de/blog/post-0/index.html"]
+	if !ok {
+		t.Fatal("Expected the post in the index, but got", len(entries), "entries")
+	}
+	if post.Title != "Post & 0" || post.Excerpt != "Excerpt 0" {
+		t.Error("Expected title and excerpt of the post, but got", post.Title, post.Excerpt)
+	}
+	if strings.Join(post.Tags, ",") != "go,static" {
+		t.Error("Expected", "go,static", ", but got", post.Tags)
+	}
+	if post.Words != "post some text of" {
+		t.Error("Expected", "post some text of", ", but got", post.Words)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Url, "/blog/index") {
+			t.Error("Expected no navi pages in the index, but got", e.Url)
+		}
+	}
+	if len(entries) <= 3 {
+		t.Error("Expected the pages of all sources, but got", len(entries), "entries")
+	}
+}
+
+func TestSearchPage(t *testing.T) {
+	files := givenSearch(t, `{"path": "/find/"}`)
+
+	page, ok := files["deploy/find/index.html"]
+	if !ok {
+		t.Fatal("Expected the search page at deploy/find/index.html, but got", len(files), "files")
+	}
+	for _, expected := range []string{
+		`<link rel="stylesheet" href="/styles.css">`,
+		`data-index="/search.json"`,
+		`<script src="/logic.js" defer></script>`} {
+		if !strings.Contains(page, expected) {
+			t.Error("Expected", expected, "in the search page, but got", page)
+		}
+	}
+}
+
+func TestSearchCanBeDisabled(t *testing.T) {
+	files := givenSearch(t, `{"disabled": true}`)
+	if len(files) != 0 {
+		t.Error("Expected no search files, but got", len(files))
+	}
+}
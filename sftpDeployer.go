@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Creates a deployer uploading via sftp
+func NewSftpDeployer(settings uploadSettings) *sftpDeployer {
+	s := new(sftpDeployer)
+	s.settings = settings
+	s.dial = s.dialSsh
+	return s
+}
+
+type sftpDeployer struct {
+	settings uploadSettings
+	dial     func() (*sftp.Client, io.Closer, error)
+	client   *sftp.Client
+	conn     io.Closer
+}
+
+func (s *sftpDeployer) Connect() error {
+	client, conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	s.client = client
+	s.conn = conn
+	return nil
+}
+
+func (s *sftpDeployer) Upload(localPath, remotePath string) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := s.client.MkdirAll(path.Dir(remotePath)); err != nil {
+		return err
+	}
+	dst, err := s.client.Create(remotePath)
+	if err != nil {
+		return err
+	}
+
+	// the server reports failed writes when
+	// closing, so the error must not be lost
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *sftpDeployer) Remove(remotePath string) error {
+	return s.client.Remove(remotePath)
+}
+
+func (s *sftpDeployer) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	s.client.Close()
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+func (s *sftpDeployer) dialSsh() (*sftp.Client, io.Closer, error) {
+	config, err := sshClientConfig(s.settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	port := s.settings.Port
+	if port == 0 {
+		port = 22
+	}
+	addr := net.JoinHostPort(s.settings.Host, strconv.Itoa(port))
+	conn, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return client, conn, nil
+}
+
+// Creates the ssh client config from the upload settings,
+// authenticating with the configured key file and,
+// if given, the password from the environment
+func sshClientConfig(settings uploadSettings) (*ssh.ClientConfig, error) {
+	hostKeyCallback, err := knownhosts.New(
+		sshFileOrDefault(settings.KnownHostsFile, "known_hosts"))
+	if err != nil {
+		return nil, err
+	}
+
+	auth := []ssh.AuthMethod{}
+	key, err := ioutil.ReadFile(sshFileOrDefault(settings.KeyFile, "id_rsa"))
+	if err == nil {
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if settings.PasswordEnv != "" {
+		auth = append(auth, ssh.Password(os.Getenv(settings.PasswordEnv)))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("neither key file nor password configured for " + settings.Host)
+	}
+
+	return &ssh.ClientConfig{
+		User:            settings.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second}, nil
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/ingmardrewing/staticPersistence"
 	log "github.com/sirupsen/logrus"
 )
@@ -13,9 +15,24 @@ func NewSitesController(configs []staticPersistence.Config) *sitesController {
 	return c
 }
 
+// Options influencing how the sites are built
+type buildOptions struct {
+	// delete files of removed pages from the deploy dir
+	prune bool
+
+	// max number of sites, sources and contexts
+	// processed at the same time
+	jobs int
+
+	// include drafts and scheduled pages
+	drafts bool
+}
+
 // the sitesController struct
 type sitesController struct {
-	configs []staticPersistence.Config
+	configs  []staticPersistence.Config
+	settings []siteSettings
+	options  buildOptions
 }
 
 // Intended for migrational purposes
@@ -25,17 +42,87 @@ func (s *sitesController) UpdateJsonFiles() {
 	}
 }
 
-// Renders the sites defined by the Json config
-func (s *sitesController) UpdateStaticSites() {
-	for _, config := range s.configs {
-		log.Debug("sites.Controller.UpdateStaticSites - Creating Site:" + config.Domain)
-		siteCreator := NewSiteCreator(config)
-		siteCreator.addSite()
-		siteCreator.addSources()
-		siteCreator.addContainers()
-		siteCreator.addLocations()
-		siteCreator.addContexts()
-		siteCreator.fillFileContainers(config)
-		siteCreator.writeFiles()
+// Renders the sites defined by the Json config,
+// up to options.jobs sites at a time
+func (s *sitesController) UpdateStaticSites() error {
+	tasks := []func() error{}
+	for i := range s.configs {
+		tasks = append(tasks, s.updateTask(i))
 	}
+	errs := buildErrors{}
+	for _, err := range runParallel(s.options.jobs, tasks) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *sitesController) updateTask(i int) func() error {
+	return func() error {
+		return s.UpdateStaticSite(i)
+	}
+}
+
+// Renders the single site defined by the part
+// of the Json config with the given index
+func (s *sitesController) UpdateStaticSite(i int) error {
+	siteCreator := s.renderSite(i)
+	siteCreator.writeFiles()
+	siteCreator.printSummary()
+	return siteCreator.errs.orNil()
+}
+
+// Renders the sites in memory, without writing them, and
+// returns the broken links found within the html files
+func (s *sitesController) CheckLinks() ([]lintFinding, error) {
+	findings := make([][]lintFinding, len(s.configs))
+	tasks := []func() error{}
+	for i := range s.configs {
+		tasks = append(tasks, s.checkLinksTask(findings, i))
+	}
+	all := []lintFinding{}
+	errs := buildErrors{}
+	for i, err := range runParallel(s.options.jobs, tasks) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		all = append(all, findings[i]...)
+	}
+	if len(errs) > 0 {
+		return all, errs
+	}
+	return all, nil
+}
+
+func (s *sitesController) checkLinksTask(findings [][]lintFinding, i int) func() error {
+	return func() error {
+		siteCreator := s.renderSite(i)
+		findings[i] = siteCreator.checkLinks()
+		return siteCreator.errs.orNil()
+	}
+}
+
+// Creates the files of the single site defined by the
+// part of the Json config with the given index in memory
+func (s *sitesController) renderSite(i int) *siteCreator {
+	config := s.configs[i]
+	log.Debug("sites.Controller.UpdateStaticSite - Creating Site:" + config.Domain)
+	siteCreator := NewSiteCreator(config)
+	siteCreator.options = s.options
+	siteCreator.settings = settingsAt(s.settings, i)
+	siteCreator.publication = NewPublication(time.Now(), s.options.drafts)
+	siteCreator.addTheme()
+	siteCreator.addSite()
+	siteCreator.addSources()
+	siteCreator.addContainers()
+	siteCreator.addLocations()
+	siteCreator.addContexts()
+	siteCreator.fillFileContainers(config)
+	siteCreator.addAssets()
+	siteCreator.addImages()
+	return siteCreator
 }
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/staticIntf"
+	"github.com/ingmardrewing/staticPersistence"
+)
+
+// Writes posts with tags, dates and images into the
+// posts dir below the given dir and returns the posts dir
+func givenTaggedPosts(dir string) string {
+	posts := filepath.Join(dir, "posts")
+	os.MkdirAll(posts, 0755)
+	items := []struct{ tags, date, images string }{
+		{"[go, static]", "2009-06-13", "[{w_800: /img/first.png}]"},
+		{"[drawing]", "2009-07-01", "[]"},
+		{"[Go]", "2010-01-05", "[{w_390: //cdn.drewing.de/third.jpg}]"}}
+	for i, p := range items {
+		ioutil.WriteFile(filepath.Join(posts, fmt.Sprintf("doc%05d.md", i)), []byte(fmt.Sprintf(
+			"---\ntitle: Post & %d\ntags: %s\ncreate_date: %s\nimages: %s\nexcerpt: Excerpt %d\npath: /blog/post-%d/\n---\nSome <em>text</em> of post %d\n",
+			i, p.tags, p.date, p.images, i, i, i)), 0644)
+	}
+	return posts
+}
+
+// Renders the site of the config returned by the given func
+// in memory, passing it the dir of the tagged posts. The config
+// is written next to the posts dir, so it isn't read as a page,
+// and the temp dir of both is removed before returning.
+func givenRenderedSite(t *testing.T, config func(posts string) string) *siteCreator {
+	dir, _ := ioutil.TempDir("", "site")
+	defer os.RemoveAll(dir)
+	posts := givenTaggedPosts(dir)
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(config(posts)), 0644)
+
+	configs := staticPersistence.ReadConfig(dir, "config.json")
+	settings, err := ReadSiteSettings(dir, "config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewSitesController(configs)
+	sc.options.jobs = 1
+	sc.settings = settings
+	s := sc.renderSite(0)
+	if err := s.errs.orNil(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Returns the rendered files of the site by their slash
+// separated paths, of all contexts if accept is nil
+func renderedFiles(s *siteCreator, accept func(ctx staticIntf.Context) bool) map[string]string {
+	fcs := s.fileContainers
+	if accept != nil {
+		fcs = nil
+		for _, ctx := range s.contexts {
+			if accept(ctx) {
+				fcs = append(fcs, ctx.RenderPages()...)
+			}
+		}
+	}
+	files := map[string]string{}
+	for _, fc := range fcs {
+		files[filepath.ToSlash(filepath.Join(fc.GetPath(), fc.GetFilename()))] = fc.GetDataAsString()
+	}
+	return files
+}
+
+func configWithTargetDir(config staticPersistence.Config, dir string) staticPersistence.Config {
+	config.Deploy.TargetDir = dir
+	return config
+}
+
+// Builds the test site serially and twice in parallel,
+// run with -race to verify the parallel build
+func TestUpdateStaticSitesInParallel(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "sites")
+	defer os.RemoveAll(dir)
+	serialDir := filepath.Join(dir, "serial")
+	parallelDirs := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+
+	sc := NewSitesController([]staticPersistence.Config{
+		configWithTargetDir(conf[0], serialDir)})
+	if err := sc.UpdateStaticSites(); err != nil {
+		t.Fatal(err)
+	}
+
+	sc = NewSitesController([]staticPersistence.Config{
+		configWithTargetDir(conf[0], parallelDirs[0]),
+		configWithTargetDir(conf[0], parallelDirs[1])})
+	sc.options.jobs = 4
+	if err := sc.UpdateStaticSites(); err != nil {
+		t.Fatal(err)
+	}
+
+	filepath.Walk(serialDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(serialDir, p)
+		expected, _ := ioutil.ReadFile(p)
+		for _, parallelDir := range parallelDirs {
+			actual, err := ioutil.ReadFile(filepath.Join(parallelDir, rel))
+			if err != nil || string(actual) != string(expected) {
+				t.Error("Expected parallel build to produce the same", rel)
+			}
+		}
+		return nil
+	})
+}
+
+func TestSitesControllerCopiesAssets(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "assets")
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"posts/doc00000.md":              "---\ntitle: Post\ncreate_date: 2009-06-13\npath: /blog/post/\n---\n[paper](/blog/post/paper.pdf) [logo](/img/logo.png) [gone](/blog/gone.pdf)\n",
+		"posts/assets/post/cover.png":    "png",
+		"posts/downloads/post/paper.pdf": "pdf",
+		"posts/downloads/.DS_Store":      "junk",
+		"static/favicon.ico":             "ico",
+		"static/img/logo.png":            "logo"}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(file), 0755)
+		ioutil.WriteFile(file, []byte(content), 0644)
+	}
+	targetDir := filepath.Join(dir, "deploy")
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(fmt.Sprintf(`[{
+		"domain": "drewing.de",
+		"deploy": {"targetDir": %q, "cssFileName": "styles.css"},
+		"src": [{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog",
+			"assets": ["assets", "downloads"]}],
+		"static": %q
+	}]`, targetDir, filepath.Join(dir, "posts"), filepath.Join(dir, "static"))), 0644)
+
+	sc := NewSitesController(staticPersistence.ReadConfig(dir, "config.json"))
+	settings, err := ReadSiteSettings(dir, "config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.settings = settings
+
+	findings, err := sc.CheckLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Message != "links to /blog/gone.pdf, which isn't generated" {
+		t.Error("Expected only the missing pdf to be reported, but got", findings)
+	}
+
+	if err := sc.UpdateStaticSites(); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"blog/post/cover.png": "png",
+		"blog/post/paper.pdf": "pdf",
+		"favicon.ico":         "ico",
+		"img/logo.png":        "logo"} {
+		data, _ := ioutil.ReadFile(filepath.Join(targetDir, filepath.FromSlash(file)))
+		if string(data) != expected {
+			t.Error("Expected", file, "to contain", expected, ", but got", string(data))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "blog", ".DS_Store")); err == nil {
+		t.Error("Expected hidden files not to be copied")
+	}
+
+	manifest, err := ReadBuildManifest(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manifest.Files["blog/post/paper.pdf"]; !ok {
+		t.Error("Expected the assets in the manifest, but got", manifest.Files)
+	}
+}
+
+func TestFailedBuildKeepsManifest(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "failed")
+	defer os.RemoveAll(dir)
+	posts := filepath.Join(dir, "posts")
+	os.MkdirAll(posts, 0755)
+	ioutil.WriteFile(filepath.Join(posts, "doc00000.md"), []byte(
+		"---\ntitle: Post\ncreate_date: 2009-06-13\npath: /blog/post/\n---\nText\n"), 0644)
+	targetDir := filepath.Join(dir, "deploy")
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(fmt.Sprintf(`[{
+		"domain": "drewing.de",
+		"deploy": {"targetDir": %q, "cssFileName": "styles.css"},
+		"src": [{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog"}]
+	}]`, targetDir, posts)), 0644)
+
+	sc := NewSitesController(staticPersistence.ReadConfig(dir, "config.json"))
+	sc.options.prune = true
+	if err := sc.UpdateStaticSites(); err != nil {
+		t.Fatal(err)
+	}
+	manifest, _ := ioutil.ReadFile(manifestPath(targetDir))
+	post := filepath.Join(targetDir, "blog", "post", "index.html")
+	if _, err := os.Stat(post); err != nil {
+		t.Fatal(err)
+	}
+
+	// a page json of the same name breaks the source
+	ioutil.WriteFile(filepath.Join(posts, "doc00000.json"), []byte("{}"), 0644)
+	err := sc.UpdateStaticSites()
+	if err == nil || !strings.Contains(err.Error(), "src[0] blog:blog:"+posts+" (type blog, dir "+posts+")") {
+		t.Error("Expected an error naming the broken source, but got", err)
+	}
+	if _, err := os.Stat(post); err != nil {
+		t.Error("Expected the post of the broken source to be kept, but got", err)
+	}
+	if data, _ := ioutil.ReadFile(manifestPath(targetDir)); string(data) != string(manifest) {
+		t.Error("Expected the manifest to be kept, but got", string(data))
+	}
+}
+
+func TestSitesOfEqualConfigsKeepTheirSettings(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "sites")
+	defer os.RemoveAll(dir)
+	posts := givenTaggedPosts(dir)
+	site := `"domain": "drewing.de",
+		"deploy": {"targetDir": "deploy"},
+		"src": [{"dir": "` + posts + `", "type": "blog", "subDir": "blog", "headline": "Blog"}]`
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`[
+		{`+site+`},
+		{`+site+`, "sitemap": {"disabled": true}}]`), 0644)
+	configs := staticPersistence.ReadConfig(dir, "config.json")
+	settings, err := ReadSiteSettings(dir, "config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewSitesController(configs)
+	sc.settings = settings
+
+	for i, expected := range []bool{true, false} {
+		sitemap := false
+		for _, ctx := range sc.renderSite(i).contexts {
+			_, ok := ctx.(*sitemapContext)
+			sitemap = sitemap || ok
+		}
+		if sitemap != expected {
+			t.Error("Expected a sitemap of site", i, ":", expected, ", but got", sitemap)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
-	"reflect"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/ingmardrewing/fs"
 	"github.com/ingmardrewing/staticIntf"
@@ -19,6 +22,11 @@ import (
 func NewSiteCreator(config staticPersistence.Config) *siteCreator {
 	siteCreator := new(siteCreator)
 	siteCreator.config = config
+	siteCreator.errs.domain = config.Domain
+	siteCreator.images = NewImagePipeline(config)
+	siteCreator.publication = NewPublication(time.Now(), false)
+	siteCreator.search = NewSearchIndex()
+	siteCreator.links = NewLinkChecker(config.Domain, config.Deploy.TargetDir)
 	return siteCreator
 }
 
@@ -36,9 +44,35 @@ func RewriteJson(config staticPersistence.Config) {
 type siteCreator struct {
 	site           staticIntf.Site
 	config         staticPersistence.Config
+	options        buildOptions
+	settings       siteSettings
 	sources        []source
 	contexts       []staticIntf.Context
 	fileContainers []fs.FileContainer
+	images         *imagePipeline
+	publication    *publication
+	search         *searchIndex
+	links          *linkChecker
+	loaders        []*pageLoader
+	srcIndices     []int
+	manifest       *buildManifest
+	due            []string
+	theme          *theme
+	templates      *pageTemplates
+	errs           siteErrors
+}
+
+// Loads the theme of the site, if it has one
+func (s *siteCreator) addTheme() {
+	if s.settings.Theme == "" {
+		return
+	}
+	t, err := LoadTheme(s.settings.ThemesDir, s.settings.Theme)
+	if err != nil {
+		s.errs.add(err)
+		return
+	}
+	s.theme = t
 }
 
 // Creates and adds a siteDto with the data
@@ -66,7 +100,16 @@ func (s *siteCreator) addSite() {
 		s.config.DefaultMeta.Author,
 		s.config.HomeText,
 		s.config.HomeHeadline,
-		s.config.SvgLogo)
+		s.svgLogo())
+}
+
+// Returns the svg logo of the config, or
+// the one of the theme if there is none
+func (s *siteCreator) svgLogo() string {
+	if s.config.SvgLogo == "" && s.theme != nil {
+		return s.theme.svgLogo
+	}
+	return s.config.SvgLogo
 }
 
 // Adds a single context to the slice of contexts
@@ -144,44 +187,171 @@ func (s *siteCreator) rewritePages() {
 func (s *siteCreator) addSources() {
 
 	log.Debugf("siteCreator.addSources(), amount: %d\n", len(s.config.Src))
-	for _, srcCfg := range s.config.Src {
-		src := NewSource(
+	for i, srcCfg := range s.config.Src {
+		src, err := NewSource(
 			srcCfg.Type,
 			srcCfg.Dir,
 			srcCfg.SubDir,
 			srcCfg.Headline,
 			s.site,
 			s.config)
+		if err != nil {
+			s.errs.add(fmt.Errorf("src[%d]: %v", i, err))
+			continue
+		}
+		loader := NewPageLoader(
+			srcCfg.Dir,
+			s.images.processDoc,
+			s.search.collectPost,
+			s.links.collectPost)
+		loader.publication = s.publication
+		src.SetPageLoader(loader)
+		src.SetSettings(s.settings.srcAt(i))
 		s.sources = append(s.sources, src)
+		s.loaders = append(s.loaders, loader)
+		s.srcIndices = append(s.srcIndices, i)
 	}
 }
 
 // Generates and stores the containers generated
-// from the sources
+// from the sources. The sources are generated in
+// parallel, but added to the site in config order.
 func (s *siteCreator) addContainers() {
 	if s.site != nil {
-		for _, src := range s.sources {
-			src.generate()
-			s.site.AddContainer(src.Container())
+		tasks := []func() error{}
+		for i, src := range s.sources {
+			tasks = append(tasks, generateTask(src, s.loaders[i]))
+		}
+		errs := runParallel(s.options.jobs, tasks)
+		for i, src := range s.sources {
+			if errs[i] != nil {
+				cfg := s.config.Src[s.srcIndices[i]]
+				s.errs.add(fmt.Errorf("src[%d] %s (type %s, dir %s): %v",
+					s.srcIndices[i], src.ID(), cfg.Type, cfg.Dir, errs[i]))
+				continue
+			}
+			src.addToSite()
 		}
 		log.Debugf("siteCreator.addContainers(), nr of added containers: %d\n", len(s.site.Containers()))
 	}
 }
 
+func generateTask(src source, loader *pageLoader) func() error {
+	return func() error {
+		src.generate()
+		return loader.Err()
+	}
+}
+
 // Generates various render contexts from and for the sources
 func (s *siteCreator) addContexts() {
 	log.Debug("siteCreator.addContexts()")
-	for _, src := range s.sources {
-		s.addContext(src.CreateContext())
+	feeds := []*feed{}
+	templates := s.pageTemplates()
+	s.templates = templates
+	views := make([]*sourceSite, len(s.sources))
+	kinds := make([]string, len(s.sources))
+	dirs := renderedDirs{}
+	feedDirs := map[string]string{}
+	for i, src := range s.sources {
+		views[i] = NewSourceSite(s.site)
+		src.SetContextSite(views[i])
+		dir := s.config.Src[s.srcIndices[i]].Dir
+		if other, ok := dirs.rendering(dir); ok {
+			log.Warnf("src[%d] %s reads the dir of %s, skipping its context to render the pages once",
+				s.srcIndices[i], src.ID(), other)
+		} else if ctx := src.CreateContext(); ctx != nil {
+			dirs.add(dir, src.ID())
+			kinds[i] = contextKind(src.ID())
+			sc := &sourceContext{
+				Context:    ctx,
+				id:         src.ID(),
+				site:       views[i],
+				containers: src.Containers(),
+				templates:  templates}
+			if asrc, ok := src.(archiveSource); ok {
+				sc.archives = asrc.ArchiveLinks()
+			}
+			s.addContext(sc)
+		}
+		if fsrc, ok := src.(feedSource); ok {
+			feeds = append(feeds, s.ownFeeds(i, fsrc.Feeds(), feedDirs)...)
+		}
+	}
+	// each context renders the containers of its own source
+	// only, among those of the sources of the same kind
+	for i := range s.sources {
+		for j, other := range s.sources {
+			if i != j && kinds[i] != "" && kinds[i] == kinds[j] {
+				views[i].hide(other.Containers()...)
+			}
+		}
 	}
+	if len(feeds) > 0 {
+		s.addContext(NewFeedContext(s.config.Deploy.TargetDir, feeds))
+	}
+	if s.site != nil && !s.settings.Sitemap.Disabled {
+		s.addContext(NewSitemapContext(s.site, s.config.Deploy.TargetDir, s.settings.Sitemap))
+	}
+	if s.site != nil && !s.settings.Search.Disabled {
+		s.addContext(NewSearchContext(
+			s.site,
+			s.search,
+			s.config.Deploy.TargetDir,
+			s.config.Deploy.CssFileName,
+			bundleJsFileName(s.config),
+			s.settings.Search))
+	}
+}
+
+// Returns the feeds of the source with the given index,
+// or none and an error if one of them would be written to
+// the dir of a feed of another source, by the source IDs
+// of the dirs of the feeds added so far
+func (s *siteCreator) ownFeeds(i int, feeds []*feed, feedDirs map[string]string) []*feed {
+	for _, f := range feeds {
+		if other, ok := feedDirs[f.dir]; ok {
+			s.errs.add(fmt.Errorf("src[%d] %s: its feeds in %q would overwrite those of %s, give one of them another subDir",
+				s.srcIndices[i], s.sources[i].ID(), "/"+f.dir, other))
+			return nil
+		}
+	}
+	for _, f := range feeds {
+		feedDirs[f.dir] = s.sources[i].ID()
+	}
+	return feeds
 }
 
-// Checks if a context already exists, to
-// avoid redundancy and double output
+// Loads the templates the pages are rendered with, those
+// of the site replacing those of the theme, and both the
+// built-in ones. Returns nil if they can't be read.
+func (s *siteCreator) pageTemplates() *pageTemplates {
+	files := []string{}
+	if s.theme != nil {
+		files = append(files, s.theme.templates()...)
+	}
+	if s.settings.Templates != "" {
+		if exists, _ := fs.PathExists(s.settings.Templates); !exists {
+			s.errs.add(fmt.Errorf("templates %s: dir not found", s.settings.Templates))
+			return nil
+		}
+		own, _ := filepath.Glob(filepath.Join(s.settings.Templates, "*.html"))
+		files = append(files, own...)
+	}
+	t, err := newPageTemplatesOf(files, s.config.Deploy.TargetDir, s.config.Deploy.CssFileName)
+	if err != nil {
+		s.errs.add(fmt.Errorf("templates: %v", err))
+		return nil
+	}
+	return t
+}
+
+// Checks if a context with the same ID already
+// exists, to avoid redundancy and double output
 func (s *siteCreator) contextExists(cg staticIntf.Context) bool {
-	contextName := reflect.TypeOf(cg).Elem().Name()
+	id := identity(cg)
 	for _, ctx := range s.contexts {
-		if contextName == reflect.TypeOf(ctx).Elem().Name() {
+		if id == identity(ctx) {
 			return true
 		}
 	}
@@ -192,33 +362,184 @@ func (s *siteCreator) contextExists(cg staticIntf.Context) bool {
 // be written
 func (s *siteCreator) fillFileContainers(config staticPersistence.Config) {
 	collector := NewComponentCollector()
-	for _, ctx := range s.contexts {
+	rendered := make([][]fs.FileContainer, len(s.contexts))
+	tasks := []func() error{}
+	for i, ctx := range s.contexts {
 		cmps := ctx.GetComponents()
 		collector.AddComponents(cmps)
-		fcs := ctx.RenderPages()
+		tasks = append(tasks, renderTask(ctx, rendered, i))
+	}
+	if s.theme != nil {
+		cmp, err := s.theme.component()
+		if err != nil {
+			s.errs.add(fmt.Errorf("theme %s: %v", s.theme.name, err))
+		} else {
+			collector.AddComponents([]staticIntf.Component{cmp})
+		}
+	}
+	if s.templates != nil && hasJs(collector.GetComponents()) {
+		s.templates.js = "/" + bundleJsFileName(config)
+	}
+	errs := runParallel(s.options.jobs, tasks)
+	for i, fcs := range rendered {
+		if errs[i] != nil {
+			s.errs.add(fmt.Errorf("rendering %s: %v", identity(s.contexts[i]), errs[i]))
+			continue
+		}
 		s.fileContainers = append(s.fileContainers, fcs...)
 	}
 
-	css := ""
-	for _, cmp := range collector.GetComponents() {
-		css += cmp.GetCss()
+	bundler := NewAssetBundler(
+		config.Domain,
+		config.Deploy.TargetDir,
+		config.Deploy.CssFileName,
+		bundleJsFileName(config),
+		s.settings.Bundle)
+	if s.theme != nil {
+		s.fileContainers = append(s.fileContainers, s.theme.fileContainers(config.Deploy.TargetDir)...)
+		bundler.AddFingerprints(s.theme.fingerprints)
+	}
+	s.fileContainers = append(s.fileContainers, bundler.Bundle(collector.GetComponents())...)
+	bundler.Rewrite(s.fileContainers)
+}
+
+// Adds the files of the asset dirs next to the page
+// json files of each source, mirrored into the deploy
+// dir below the subDir of the source, and the files of
+// the static dir of the site, mirrored into the deploy dir
+func (s *siteCreator) addAssets() {
+	added := map[string]bool{}
+	for i, src := range s.config.Src {
+		targetDir := filepath.Join(s.config.Deploy.TargetDir, src.SubDir)
+		for _, name := range s.settings.srcAt(i).assetDirs() {
+			assetDir := filepath.Join(src.Dir, name)
+			key := assetDir + "|" + src.SubDir
+			if added[key] {
+				continue
+			}
+			added[key] = true
+			s.errs.add(s.addAssetDir(assetDir, targetDir))
+		}
 	}
 
-	cssFc := fs.NewFileContainer()
-	cssFc.SetDataAsString(css)
-	cssFc.SetPath(config.Deploy.TargetDir)
-	cssFc.SetFilename(config.Deploy.CssFileName)
-	s.fileContainers = append(s.fileContainers, cssFc)
+	if s.settings.Static != "" {
+		if exists, _ := fs.PathExists(s.settings.Static); !exists {
+			s.errs.add(fmt.Errorf("static dir %s not found", s.settings.Static))
+			return
+		}
+		s.errs.add(s.addAssetDir(s.settings.Static, s.config.Deploy.TargetDir))
+	}
+}
+
+func (s *siteCreator) addAssetDir(assetDir, targetDir string) error {
+	exists, _ := fs.PathExists(assetDir)
+	if !exists {
+		return nil
+	}
+	return filepath.Walk(assetDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && p != assetDir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(assetDir, p)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		fc := fs.NewFileContainer()
+		fc.SetDataAsString(string(data))
+		fc.SetPath(filepath.Join(targetDir, filepath.Dir(rel)))
+		fc.SetFilename(filepath.Base(rel))
+		s.fileContainers = append(s.fileContainers, fc)
+		return nil
+	})
+}
+
+// Adds the images published while
+// loading the pages of the sources
+func (s *siteCreator) addImages() {
+	s.fileContainers = append(s.fileContainers, s.images.FileContainers()...)
+}
+
+// Checks the links of the rendered files, which
+// have to be complete, i.e. include assets and images
+func (s *siteCreator) checkLinks() []lintFinding {
+	s.links.allowedHosts = s.settings.CheckLinks.AllowedHosts
+	return s.links.Check(s.fileContainers)
+}
+
+// Renders the pages of the given context into
+// the given slot of the result slice
+func renderTask(ctx staticIntf.Context, results [][]fs.FileContainer, i int) func() error {
+	return func() error {
+		results[i] = ctx.RenderPages()
+		return nil
+	}
 }
 
 // Actually writes the files of the website to
-// the local file system
+// the local file system. Files whose content hasn't
+// changed since the last build are skipped.
 func (s *siteCreator) writeFiles() {
 	msg := fmt.Sprintf("Number of files to write: %d", len(s.fileContainers))
 	log.Debug(msg)
+
+	targetDir := s.config.Deploy.TargetDir
+	last, err := ReadBuildManifest(targetDir)
+	if err != nil {
+		log.Warn("siteCreator.writeFiles() - ignoring unreadable manifest: ", err)
+		last = NewBuildManifest(targetDir)
+	}
+
+	manifest := NewBuildManifest(targetDir)
 	for _, f := range s.fileContainers {
+		rel := manifest.relPath(f)
+		if !manifest.add(rel, contentHash(f.GetDataAsString()), last) {
+			continue
+		}
 		log.Debug("Writing file: " + f.GetPath() + "/" + f.GetFilename())
 		//log.Debug(f.GetDataAsString())
 		f.Write()
 	}
+
+	// The files of a broken source are missing from the build,
+	// so they are neither removed nor pruned, and the manifest
+	// of the last build is kept to deploy from
+	if err := s.errs.orNil(); err != nil {
+		log.Warn("siteCreator.writeFiles() - keeping the last manifest, the build failed: ", err)
+		return
+	}
+	s.manifest = manifest
+	s.manifest.collectRemoved(last)
+	s.manifest.Scheduled = s.publication.Scheduled()
+	s.manifest.Drafts = s.publication.drafts
+	s.due = s.publication.Due(last.Scheduled)
+
+	if s.options.prune {
+		s.errs.add(s.manifest.prune())
+	}
+	s.errs.add(s.manifest.Write())
+}
+
+// Prints which files have been added, changed
+// and removed by the last call of writeFiles
+func (s *siteCreator) printSummary() {
+	if s.manifest == nil {
+		return
+	}
+	fmt.Println(s.config.Domain + ": " + s.manifest.Summary(s.options.prune))
+	for _, location := range s.due {
+		fmt.Println("  now due: " + location)
+	}
 }
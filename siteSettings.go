@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+)
+
+// Reads the settings not covered by staticPersistence.Config
+// from the same json config file. The n-th entry of the
+// returned slice belongs to the n-th site of the config.
+func ReadSiteSettings(dir, file string) ([]siteSettings, error) {
+	data, err := ioutil.ReadFile(path.Join(dir, file))
+	if err != nil {
+		return nil, err
+	}
+	settings := []siteSettings{}
+	err = json.Unmarshal(data, &settings)
+	return settings, err
+}
+
+// Returns the settings of the site with the given
+// index, or empty settings if there are none
+func settingsAt(settings []siteSettings, i int) siteSettings {
+	if i < len(settings) {
+		return settings[i]
+	}
+	return siteSettings{}
+}
+
+// Additional settings of one site
+type siteSettings struct {
+	Deploy     deploySettings    `json:"deploy"`
+	Src        []srcSettings     `json:"src"`
+	Sitemap    sitemapSettings   `json:"sitemap"`
+	Search     searchSettings    `json:"search"`
+	CheckLinks linkCheckSettings `json:"checkLinks"`
+	Bundle     bundleSettings    `json:"bundle"`
+
+	// dir of html/template files overriding the
+	// rendering of the page types named like them
+	Templates string `json:"templates"`
+
+	// name of the theme of the site, looked up in
+	// the themes dir, defaulting to themes/
+	Theme     string `json:"theme"`
+	ThemesDir string `json:"themesDir"`
+
+	// dir whose contents are mirrored into
+	// the deploy dir, like favicon.ico
+	Static string `json:"static"`
+}
+
+// Returns the settings of the source with the given
+// index, or empty settings if there are none
+func (s siteSettings) srcAt(i int) srcSettings {
+	if i < len(s.Src) {
+		return s.Src[i]
+	}
+	return srcSettings{}
+}
+
+const (
+	defaultPageSize  = 10
+	paginationIndex  = "index"
+	paginationStable = "stable"
+)
+
+// Additional settings of one source, the n-th
+// entry belongs to the n-th source of the site
+type srcSettings struct {
+	// number of posts per navi page,
+	// defaults to defaultPageSize
+	PageSize int `json:"pageSize"`
+
+	// either paginationIndex, the default, naming the navi
+	// pages index0.html … indexN.html and index.html for
+	// the newest posts, or paginationStable, naming them
+	// page/1/ … page/N/ counted from the oldest posts
+	Pagination string `json:"pagination"`
+
+	Representationals representationalSettings `json:"representationals"`
+
+	Feed feedSettings `json:"feed"`
+
+	// dirs next to the page json files, whose contents are
+	// mirrored into the deploy dir below the subDir of the
+	// source, defaults to assetsDirName
+	Assets []string `json:"assets"`
+}
+
+// Name of the dir next to the page json files holding
+// files which are copied into the deploy dir as they are
+const assetsDirName = "assets"
+
+// Returns the asset dirs of the source
+func (s srcSettings) assetDirs() []string {
+	if len(s.Assets) == 0 {
+		return []string{assetsDirName}
+	}
+	return s.Assets
+}
+
+// Defines the Atom and JSON feeds of blogs and narratives
+type feedSettings struct {
+	// either feedFull, the default, putting the whole
+	// content into the feeds, or feedExcerpt, putting
+	// only the excerpt of each page into them
+	Content string `json:"content"`
+
+	// number of the newest pages in each feed,
+	// -1 for all, defaults to defaultFeedLimit
+	Limit int `json:"limit"`
+}
+
+// Defines which pages represent a source, e.g. as teasers
+// on the home page. Without any of them, blogs and narratives
+// are represented only if they have more than 4 pages.
+type representationalSettings struct {
+	// number of pages, -1 for all, defaults to
+	// all for portfolios and 4 for other sources
+	Count int `json:"count"`
+
+	// one of pickLatest, the default,
+	// pickFeatured, pickRandom or pickTag
+	Strategy string `json:"strategy"`
+
+	// the tag picked by pickTag
+	Tag string `json:"tag"`
+}
+
+// Defines the sitemap.xml and robots.txt of a site
+type sitemapSettings struct {
+	// generates neither sitemap.xml nor robots.txt
+	Disabled bool `json:"disabled"`
+
+	// generates no robots.txt, e.g. to keep
+	// a handwritten one from the assets
+	NoRobots bool `json:"noRobots"`
+
+	// paths from the doc root left out of the
+	// sitemap and disallowed by robots.txt
+	Exclude []string `json:"exclude"`
+}
+
+// Defines the search index and search page of a site
+type searchSettings struct {
+	// generates neither search.json nor the search page
+	Disabled bool `json:"disabled"`
+
+	// path of the search page from the doc
+	// root, defaults to defaultSearchPath
+	Path string `json:"path"`
+}
+
+// Defines how -checklinks treats external links
+type linkCheckSettings struct {
+	// hosts external links may point to, subdomains
+	// included. Links to other hosts are reported,
+	// external links aren't checked if it is empty.
+	AllowedHosts []string `json:"allowedHosts"`
+}
+
+// Defines the css and js bundles of a site
+type bundleSettings struct {
+	// writes a source map next to each bundle
+	SourceMaps bool `json:"sourceMaps"`
+}
+
+// Additional settings of the deploy section
+type deploySettings struct {
+	Upload uploadSettings `json:"upload"`
+}
+
+// Defines how and where the generated files
+// of a site are uploaded
+type uploadSettings struct {
+	// one of sftp, ftp, ftps or rsync
+	Protocol  string `json:"protocol"`
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+	User      string `json:"user"`
+	RemoteDir string `json:"remoteDir"`
+
+	// name of the environment variable
+	// holding the password
+	PasswordEnv string `json:"passwordEnv"`
+
+	// private key used by sftp and rsync,
+	// defaults to ~/.ssh/id_rsa
+	KeyFile string `json:"keyFile"`
+
+	// defaults to ~/.ssh/known_hosts
+	KnownHostsFile string `json:"knownHostsFile"`
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticIntf"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	sitemapFilename = "sitemap.xml"
+	robotsFilename  = "robots.txt"
+	sitemapXmlns    = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// Number of urls per sitemap file, larger sites
+// get several files listed by a sitemap index
+var sitemapMaxUrls = 50000
+
+// Creates the context rendering the sitemap and
+// robots.txt of the given site into the target dir
+func NewSitemapContext(
+	site staticIntf.Site,
+	targetDir string,
+	settings sitemapSettings) *sitemapContext {
+
+	c := new(sitemapContext)
+	c.site = site
+	c.targetDir = targetDir
+	c.settings = settings
+	return c
+}
+
+// The sitemapContext renders the sitemap of all pages
+// and internal locations of a site, split into several
+// files and a sitemap index if necessary, and a
+// robots.txt referencing it
+type sitemapContext struct {
+	site      staticIntf.Site
+	targetDir string
+	settings  sitemapSettings
+}
+
+// A url listed by the sitemap
+type sitemapUrl struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapUrlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Urls    []sitemapUrl `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapUrl `xml:"sitemap"`
+}
+
+// The sitemap needs no css
+func (c *sitemapContext) GetComponents() []staticIntf.Component {
+	return []staticIntf.Component{}
+}
+
+func (c *sitemapContext) RenderPages() []fs.FileContainer {
+	fcs := []fs.FileContainer{}
+	urls := c.urls()
+
+	if len(urls) <= sitemapMaxUrls {
+		fcs = c.appendXml(fcs, sitemapFilename, sitemapUrlSet{Xmlns: sitemapXmlns, Urls: urls})
+	} else {
+		index := sitemapIndex{Xmlns: sitemapXmlns}
+		for i := 0; i*sitemapMaxUrls < len(urls); i++ {
+			end := (i + 1) * sitemapMaxUrls
+			if end > len(urls) {
+				end = len(urls)
+			}
+			part := urls[i*sitemapMaxUrls : end]
+			filename := "sitemap-" + strconv.Itoa(i+1) + ".xml"
+			fcs = c.appendXml(fcs, filename, sitemapUrlSet{Xmlns: sitemapXmlns, Urls: part})
+			index.Sitemaps = append(index.Sitemaps, sitemapUrl{
+				Loc:     c.fileUrl(filename),
+				LastMod: newestLastMod(part)})
+		}
+		fcs = c.appendXml(fcs, sitemapFilename, index)
+	}
+
+	if !c.settings.NoRobots {
+		fc := fs.NewFileContainer()
+		fc.SetDataAsString(c.robots())
+		fc.SetPath(c.targetDir)
+		fc.SetFilename(robotsFilename)
+		fcs = append(fcs, fc)
+	}
+	return fcs
+}
+
+func (c *sitemapContext) fileUrl(filename string) string {
+	return "https://" + c.site.Domain() + "/" + filename
+}
+
+// Collects the urls of all pages, navi pages and internal
+// locations of the site, in the order of the containers
+func (c *sitemapContext) urls() []sitemapUrl {
+	urls := []sitemapUrl{}
+	seen := map[string]bool{}
+	add := func(url, lastMod string) {
+		if seen[url] || !c.isListed(url) {
+			return
+		}
+		seen[url] = true
+		urls = append(urls, sitemapUrl{Loc: url, LastMod: lastMod})
+	}
+
+	for _, container := range c.site.Containers() {
+		for _, p := range container.Pages() {
+			add(p.Url(), lastMod(p))
+		}
+		for _, p := range container.NaviPages() {
+			add(p.Url(), lastMod(p.NavigatedPages()...))
+		}
+	}
+	for _, locations := range [][]staticIntf.Location{c.site.Main(), c.site.Marginal()} {
+		for _, l := range locations {
+			if l.ExternalLink() == "" {
+				add(l.Url(), "")
+			}
+		}
+	}
+	return urls
+}
+
+// Tells if the url belongs to the site and
+// isn't excluded by the settings
+func (c *sitemapContext) isListed(url string) bool {
+	root := "https://" + c.site.Domain()
+	if url != root && !strings.HasPrefix(url, root+"/") {
+		return false
+	}
+	pth := "/" + strings.TrimPrefix(strings.TrimPrefix(url, root), "/")
+	for _, exclude := range c.settings.Exclude {
+		if strings.HasPrefix(pth, exclude) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the robots.txt allowing all pages but the
+// excluded ones and referencing the sitemap
+func (c *sitemapContext) robots() string {
+	lines := []string{"User-agent: *"}
+	if len(c.settings.Exclude) == 0 {
+		lines = append(lines, "Disallow:")
+	}
+	for _, exclude := range c.settings.Exclude {
+		lines = append(lines, "Disallow: "+exclude)
+	}
+	lines = append(lines, "", "Sitemap: "+c.fileUrl(sitemapFilename))
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (c *sitemapContext) appendXml(fcs []fs.FileContainer, filename string, doc interface{}) []fs.FileContainer {
+	data, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		log.Errorf("rendering %s: %v", filename, err)
+		return fcs
+	}
+	fc := fs.NewFileContainer()
+	fc.SetData(append([]byte(xml.Header), data...))
+	fc.SetPath(c.targetDir)
+	fc.SetFilename(filename)
+	return append(fcs, fc)
+}
+
+// Returns the newest creation date of the given
+// pages as W3C date, or "" if none is known
+func lastMod(pages ...staticIntf.Page) string {
+	newest := time.Time{}
+	for _, p := range pages {
+		if date, ok := pageDate(p.PublishedTime()); ok && date.After(newest) {
+			newest = date
+		}
+	}
+	if newest.IsZero() {
+		return ""
+	}
+	return newest.Format("2006-01-02")
+}
+
+func newestLastMod(urls []sitemapUrl) string {
+	newest := ""
+	for _, u := range urls {
+		if u.LastMod > newest {
+			newest = u.LastMod
+		}
+	}
+	return newest
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/staticIntf"
+)
+
+// Builds a site with the tagged posts and the given sitemap
+// settings and returns its rendered sitemap files by name
+func givenSitemap(t *testing.T, sitemap string) map[string]string {
+	s := givenRenderedSite(t, func(posts string) string {
+		return fmt.Sprintf(`[{
+			"domain": "drewing.de",
+			"deploy": {"targetDir": "deploy"},
+			"context": {
+				"mainLinks": [
+					{"label": "Blog", "path": "/blog/", "fileName": "index.html"},
+					{"label": "Shop", "externalLink": "https://shop.example.com/"}
+				]
+			},
+			"src": [{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog"}],
+			"sitemap": %s
+		}]`, posts, sitemap)
+	})
+	files := map[string]string{}
+	for name, data := range renderedFiles(s, func(ctx staticIntf.Context) bool {
+		_, ok := ctx.(*sitemapContext)
+		return ok
+	}) {
+		files[path.Base(name)] = data
+	}
+	return files
+}
+
+func TestSitemapListsPagesWithLastMod(t *testing.T) {
+	files := givenSitemap(t, `{}`)
+
+	set := new(sitemapUrlSet)
+	if err := xml.Unmarshal([]byte(files["sitemap.xml"]), set); err != nil {
+		t.Fatal(err)
+	}
+	if set.XMLName.Space != sitemapXmlns {
+		t.Error("Expected", sitemapXmlns, ", but got", set.XMLName.Space)
+	}
+
+	urls := map[string]string{}
+	for _, u := range set.Urls {
+		if _, ok := urls[u.Loc]; ok {
+			t.Error("Expected each url once, but got", u.Loc, "twice")
+		}
+		urls[u.Loc] = u.LastMod
+	}
+	if lastMod := urls["https://drewing.de/blog/post-0/index.html"]; lastMod != "2009-06-13" {
+		t.Error("Expected", "2009-06-13", ", but got", lastMod)
+	}
+	if lastMod := urls["https://drewing.de/blog/index.html"]; lastMod != "2010-01-05" {
+		t.Error("Expected the newest post of the navi page", "2010-01-05", ", but got", lastMod)
+	}
+	for url := range urls {
+		if !strings.HasPrefix(url, "https://drewing.de/") {
+			t.Error("Expected only urls of the site, but got", url)
+		}
+	}
+
+	expected := "User-agent: *\nDisallow:\n\nSitemap: https://drewing.de/sitemap.xml\n"
+	if files["robots.txt"] != expected {
+		t.Error("Expected", expected, ", but got", files["robots.txt"])
+	}
+}
+
+func TestSitemapExcludesPaths(t *testing.T) {
+	files := givenSitemap(t, `{"exclude": ["/blog/tags/"], "noRobots": true}`)
+
+	if strings.Contains(files["sitemap.xml"], "/blog/tags/") {
+		t.Error("Expected no excluded urls, but got", files["sitemap.xml"])
+	}
+	if _, ok := files["robots.txt"]; ok {
+		t.Error("Expected no robots.txt, but got", files["robots.txt"])
+	}
+}
+
+func TestSitemapIsSplitIntoIndex(t *testing.T) {
+	max := sitemapMaxUrls
+	sitemapMaxUrls = 5
+	defer func() { sitemapMaxUrls = max }()
+
+	files := givenSitemap(t, `{}`)
+	index := new(sitemapIndex)
+	if err := xml.Unmarshal([]byte(files["sitemap.xml"]), index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Sitemaps) < 2 {
+		t.Fatal("Expected several sitemaps, but got", len(index.Sitemaps))
+	}
+	for i, sm := range index.Sitemaps {
+		name := fmt.Sprintf("sitemap-%d.xml", i+1)
+		if sm.Loc != "https://drewing.de/"+name {
+			t.Error("Expected", "https://drewing.de/"+name, ", but got", sm.Loc)
+		}
+		set := new(sitemapUrlSet)
+		if err := xml.Unmarshal([]byte(files[name]), set); err != nil {
+			t.Error(err)
+		}
+		if len(set.Urls) == 0 || len(set.Urls) > 5 {
+			t.Error("Expected 1 to 5 urls in", name, ", but got", len(set.Urls))
+		}
+	}
+}
+
+func TestSitemapCanBeDisabled(t *testing.T) {
+	files := givenSitemap(t, `{"disabled": true}`)
+	if len(files) != 0 {
+		t.Error("Expected no sitemap files, but got", len(files))
+	}
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/ingmardrewing/staticIntf"
 	"github.com/ingmardrewing/staticModel"
@@ -14,62 +17,135 @@ import (
 //
 type source interface {
 	generate()
+	addToSite()
 	Container() staticIntf.PagesContainer
+	Containers() []staticIntf.PagesContainer
 	CreateContext() staticIntf.Context
+	ID() string
+	SetContextSite(site staticIntf.Site)
 	SetData(variant, headline, dir, subDir string, site staticIntf.Site, config staticPersistence.Config)
+	SetPageLoader(loader *pageLoader)
+	SetSettings(settings srcSettings)
+}
+
+// Creates a new, empty source of one variant
+type SourceFactory func() source
+
+var (
+	sourceFactories   = map[string]SourceFactory{}
+	sourceFactoriesMu sync.RWMutex
+)
+
+func init() {
+	RegisterSource(staticIntf.HOME, func() source { return new(homeSource) })
+	RegisterSource(staticIntf.BLOG, func() source { return new(blogSource) })
+	RegisterSource(staticIntf.PORTFOLIO, func() source { return new(portfolioSource) })
+	RegisterSource(staticIntf.MARGINALS, func() source { return new(marginalSource) })
+	RegisterSource(staticIntf.NARRATIVES, func() source { return new(narrativeSource) })
+	RegisterSource(staticIntf.NARRATIVEMARGINALS, func() source { return new(narrativeMarginalSource) })
+}
+
+// Registers the factory of a source variant, which
+// can then be used as type of a src entry in the config.
+// Custom sources embed defaultSource and return their
+// own staticIntf.Context from CreateContext. Registering
+// a variant again replaces its factory.
+func RegisterSource(variant string, factory SourceFactory) {
+	sourceFactoriesMu.Lock()
+	defer sourceFactoriesMu.Unlock()
+	sourceFactories[variant] = factory
+}
+
+// Returns the sorted names of all registered variants
+func RegisteredSourceVariants() []string {
+	sourceFactoriesMu.RLock()
+	defer sourceFactoriesMu.RUnlock()
+	variants := []string{}
+	for v := range sourceFactories {
+		variants = append(variants, v)
+	}
+	sort.Strings(variants)
+	return variants
+}
+
+// Tells if a factory for the given variant is registered
+func isRegisteredSource(variant string) bool {
+	sourceFactoriesMu.RLock()
+	defer sourceFactoriesMu.RUnlock()
+	_, ok := sourceFactories[variant]
+	return ok
 }
 
 func NewSource(
 	variant, dir, subDir, headline string,
 	site staticIntf.Site,
-	config staticPersistence.Config) source {
+	config staticPersistence.Config) (source, error) {
 
 	log.Debugf("NewSource() called for variant %s\n", variant)
-	var s source
-	switch variant {
-	case staticIntf.HOME:
-		s = new(homeSource)
-	case staticIntf.BLOG:
-		s = new(blogSource)
-	case staticIntf.PORTFOLIO:
-		s = new(portfolioSource)
-	case staticIntf.MARGINALS:
-		s = new(marginalSource)
-	case staticIntf.NARRATIVES:
-		s = new(narrativeSource)
-	case staticIntf.NARRATIVEMARGINALS:
-		s = new(narrativeMarginalSource)
-	default:
-		log.Fatal("unaccounted variant:", variant)
+	sourceFactoriesMu.RLock()
+	factory, ok := sourceFactories[variant]
+	sourceFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown source type %q, registered types are: %s",
+			variant, strings.Join(RegisteredSourceVariants(), ", "))
 	}
 
+	s := factory()
 	s.SetData(variant, headline, dir, subDir, site, config)
 
-	return s
+	return s, nil
 }
 
 type blogSource struct {
 	defaultSource
+	archives     []staticIntf.PagesContainer
+	archiveLinks map[string]archiveLinks
+	feeds        []*feed
 }
 
 func (bs *blogSource) generate() {
-	bs.generateContainer()
+	archive := NewBlogArchive(bs.site, bs.subDir)
+	archive.settings = bs.settings
+	picker := NewRepresentationalPicker(bs.settings.Representationals, 4)
+	feeds := bs.newFeedBuilder()
+	feeds.tagArchives = true
+	bs.generateContainer(archive.collectPost, picker.collectPost, feeds.collectPost)
 
 	bnpg := NewBlogNaviPageGenerator(
 		bs.site,
 		bs.subDir,
 		bs.container)
+	bnpg.useSettings(bs.settings)
 	naviPages := bnpg.Createpages()
 	for _, p := range naviPages {
 		bs.container.AddNaviPage(p)
 		p.Container(bs.container)
 	}
-	pages := bs.container.Pages()
-	nrOfRepPages := 4
-	if len(pages) > nrOfRepPages {
-		for _, pg := range pages[len(pages)-nrOfRepPages:] {
-			bs.container.AddRepresentational(pg)
-		}
+	bs.addRepresentationals(picker)
+	bs.archives = archive.Containers(bs.container)
+	bs.archiveLinks = archive.Links()
+	bs.feeds = feeds.Feeds(bs.container)
+}
+
+func (bs *blogSource) Feeds() []*feed {
+	return bs.feeds
+}
+
+func (bs *blogSource) ArchiveLinks() map[string]archiveLinks {
+	return bs.archiveLinks
+}
+
+// Returns the blog and its archives
+func (bs *blogSource) Containers() []staticIntf.PagesContainer {
+	return append(bs.defaultSource.Containers(), bs.archives...)
+}
+
+// Adds the archives of the blog to the
+// site, after the blog itself
+func (bs *blogSource) addToSite() {
+	bs.defaultSource.addToSite()
+	for _, c := range bs.archives {
+		bs.site.AddContainer(c)
 	}
 }
 
@@ -83,13 +159,11 @@ type portfolioSource struct {
 }
 
 func (ps *portfolioSource) generate() {
-	ps.generateContainer()
+	picker := NewRepresentationalPicker(ps.settings.Representationals, -1)
+	ps.generateContainer(picker.collectPost)
 
-	pages := ps.container.Pages()
-	log.Debugf("portfolioSource.generate() with %d pages\n", len(pages))
-	for _, pg := range pages {
-		ps.container.AddRepresentational(pg)
-	}
+	log.Debugf("portfolioSource.generate() with %d pages\n", len(ps.container.Pages()))
+	ps.addRepresentationals(picker)
 }
 
 func (ps *portfolioSource) CreateContext() staticIntf.Context {
@@ -127,11 +201,14 @@ type marginalSource struct {
 
 func (mrs *marginalSource) generate() {
 	mrs.generateContainer()
+}
+
+func (mrs *marginalSource) addToSite() {
+	mrs.defaultSource.addToSite()
 	locs := ElementsToLocations(mrs.container.Pages())
 	for _, l := range locs {
 		mrs.site.AddMarginal(l)
 	}
-
 }
 
 func (mrs *marginalSource) CreateContext() staticIntf.Context {
@@ -141,18 +218,19 @@ func (mrs *marginalSource) CreateContext() staticIntf.Context {
 //
 type narrativeSource struct {
 	defaultSource
+	feeds []*feed
 }
 
 func (ns *narrativeSource) generate() {
-	ns.generateContainer()
-
-	pages := ns.container.Pages()
-	nrOfRepPages := 4
-	if len(pages) > nrOfRepPages {
-		for _, pg := range pages[len(pages)-nrOfRepPages:] {
-			ns.container.AddRepresentational(pg)
-		}
-	}
+	picker := NewRepresentationalPicker(ns.settings.Representationals, 4)
+	feeds := ns.newFeedBuilder()
+	ns.generateContainer(picker.collectPost, feeds.collectPost)
+	ns.addRepresentationals(picker)
+	ns.feeds = feeds.Feeds(ns.container)
+}
+
+func (ns *narrativeSource) Feeds() []*feed {
+	return ns.feeds
 }
 
 func (ns *narrativeSource) CreateContext() staticIntf.Context {
@@ -169,6 +247,8 @@ type defaultSource struct {
 	site      staticIntf.Site
 	config    staticPersistence.Config
 	container staticIntf.PagesContainer
+	loader    *pageLoader
+	settings  srcSettings
 }
 
 func (a *defaultSource) CreateContext() staticIntf.Context {
@@ -179,8 +259,35 @@ func (a *defaultSource) Container() staticIntf.PagesContainer {
 	return a.container
 }
 
+// Returns the containers the source adds to the site
+func (a *defaultSource) Containers() []staticIntf.PagesContainer {
+	if a.container == nil {
+		return nil
+	}
+	return []staticIntf.PagesContainer{a.container}
+}
+
+// Identifies the source, and so its context, by its
+// variant and the dirs it reads from and renders to
+func (a *defaultSource) ID() string {
+	return a.variant + ":" + a.subDir + ":" + a.dir
+}
+
+// Replaces the site by the view of it the context is
+// created with, after the containers have been added
+func (a *defaultSource) SetContextSite(site staticIntf.Site) {
+	a.site = site
+}
+
 func (a *defaultSource) generate() {}
 
+// Adds the generated container to the site. Sources
+// may be generated concurrently, so all changes of
+// the site have to happen here.
+func (a *defaultSource) addToSite() {
+	a.site.AddContainer(a.container)
+}
+
 func (a *defaultSource) SetData(variant, headline, dir, subDir string, site staticIntf.Site, config staticPersistence.Config) {
 	a.variant = variant
 	a.headline = headline
@@ -188,18 +295,50 @@ func (a *defaultSource) SetData(variant, headline, dir, subDir string, site stat
 	a.subDir = subDir
 	a.site = site
 	a.config = config
+	a.loader = NewPageLoader(dir)
+}
+
+// Replaces the loader reading the page json
+// and markdown files of the source dir
+func (a *defaultSource) SetPageLoader(loader *pageLoader) {
+	a.loader = loader
+}
+
+// Sets the additional settings of the source
+func (a *defaultSource) SetSettings(settings srcSettings) {
+	a.settings = settings
 }
 
-func (a *defaultSource) generateContainer() {
+// Creates the container from the pages of the source dir,
+// the given transforms are applied to the page documents
+func (a *defaultSource) generateContainer(transforms ...pageTransform) {
 	log.Debug(fmt.Sprintf("-- new container, type %s, headline %s", a.variant, a.headline))
 	a.container = staticModel.NewPagesContainer(a.variant, a.headline)
-	pageDtos := staticPersistence.ReadPagesFromDir(a.dir)
+	pageDtos := a.loader.Load(transforms...)
 	log.Debugf("defaultSource.generateContainer() with %d pageDtos", len(pageDtos))
 	for _, dto := range pageDtos {
 		a.createPage(dto)
 	}
 }
 
+// Adds the pages chosen by the picker
+// as representationals of the container
+func (a *defaultSource) addRepresentationals(picker *representationalPicker) {
+	for _, pg := range picker.Pick(a.container.Pages()) {
+		a.container.AddRepresentational(pg)
+	}
+}
+
+// Creates a feedBuilder for the pages of the source
+func (a *defaultSource) newFeedBuilder() *feedBuilder {
+	return NewFeedBuilder(
+		a.site,
+		a.subDir,
+		a.headline,
+		a.config.DefaultMeta.Author,
+		a.settings.Feed)
+}
+
 func (a *defaultSource) createPage(dto staticIntf.PageDto) {
 	p := staticModel.NewPage(dto, a.site)
 	if p == nil {
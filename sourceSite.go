@@ -0,0 +1,117 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/ingmardrewing/fs"
+	"github.com/ingmardrewing/staticIntf"
+)
+
+// Contexts and components can tell their ID. Two of
+// them with the same ID are redundant, only the first
+// one is used.
+type identified interface {
+	ID() string
+}
+
+// Returns the ID of the context or component, or
+// the name of its type if it doesn't tell one
+func identity(x interface{}) string {
+	if i, ok := x.(identified); ok {
+		return i.ID()
+	}
+	return reflect.TypeOf(x).String()
+}
+
+// Returns the kind of the context of the given ID, the
+// variant of its source. The contexts of one kind render
+// the containers of all sources of that kind.
+func contextKind(id string) string {
+	return strings.SplitN(id, ":", 2)[0]
+}
+
+// The dirs read by the sources creating a context, by
+// the ID of the context. The pages tell their paths, so
+// the pages of one dir are rendered to the same paths by
+// each context, whatever the subDir of its source.
+type renderedDirs map[string]string
+
+func (r renderedDirs) add(dir, id string) {
+	r[filepath.Clean(dir)] = id
+}
+
+// Returns the ID of the context rendering the pages of
+// the given dir, if there is one
+func (r renderedDirs) rendering(dir string) (string, bool) {
+	id, ok := r[filepath.Clean(dir)]
+	return id, ok
+}
+
+// Creates the view of the site a source creates its context
+// with, showing all containers of the site but the hidden ones
+func NewSourceSite(site staticIntf.Site) *sourceSite {
+	s := new(sourceSite)
+	s.Site = site
+	s.hidden = map[staticIntf.PagesContainer]bool{}
+	return s
+}
+
+// The sourceSite hides the containers of the other sources
+// of the same kind of context, so several blog or narrative
+// sources of one site render their pages once each, while
+// the home context still sees all of them
+type sourceSite struct {
+	staticIntf.Site
+	hidden map[staticIntf.PagesContainer]bool
+}
+
+func (s *sourceSite) hide(containers ...staticIntf.PagesContainer) {
+	for _, c := range containers {
+		s.hidden[c] = true
+	}
+}
+
+func (s *sourceSite) Containers() []staticIntf.PagesContainer {
+	return s.visible(s.Site.Containers())
+}
+
+func (s *sourceSite) ContainersOrderedByVariants(variants ...string) []staticIntf.PagesContainer {
+	return s.visible(s.Site.ContainersOrderedByVariants(variants...))
+}
+
+func (s *sourceSite) visible(containers []staticIntf.PagesContainer) []staticIntf.PagesContainer {
+	visible := []staticIntf.PagesContainer{}
+	for _, c := range containers {
+		if !s.hidden[c] {
+			visible = append(visible, c)
+		}
+	}
+	return visible
+}
+
+// Wraps the context of a source, to identify it by the
+// source instead of the type of the context, and to render
+// its pages with the templates of the site, which link the
+// posts to their archives
+type sourceContext struct {
+	staticIntf.Context
+	id         string
+	site       staticIntf.Site
+	containers []staticIntf.PagesContainer
+	archives   map[string]archiveLinks
+	templates  *pageTemplates
+}
+
+func (c *sourceContext) ID() string {
+	return c.id
+}
+
+func (c *sourceContext) RenderPages() []fs.FileContainer {
+	fcs := c.Context.RenderPages()
+	if c.templates != nil {
+		c.templates.apply(fcs, c.containers, c.site, c.archives)
+	}
+	return fcs
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ingmardrewing/staticIntf"
+	"github.com/ingmardrewing/staticPersistence"
+	"github.com/ingmardrewing/staticPresentation"
+)
+
+type gallerySource struct {
+	defaultSource
+}
+
+func (gs *gallerySource) generate() { gs.generateContainer() }
+
+func (gs *gallerySource) CreateContext() staticIntf.Context {
+	return staticPresentation.NewPortfolioContext(gs.site)
+}
+
+func TestRegisterSource(t *testing.T) {
+	RegisterSource("gallery", func() source { return new(gallerySource) })
+	defer func() {
+		sourceFactoriesMu.Lock()
+		delete(sourceFactories, "gallery")
+		sourceFactoriesMu.Unlock()
+	}()
+
+	src, err := NewSource("gallery", "testResources/src/portfolio/", "", "",
+		nil, staticPersistence.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := src.(*gallerySource); !ok {
+		t.Errorf("Expected gallerySource, but got %T", src)
+	}
+}
+
+func TestNewSourceUnknownVariant(t *testing.T) {
+	_, err := NewSource("podcast", "", "", "", nil, staticPersistence.Config{})
+	if err == nil {
+		t.Fatal("Expected error for unregistered variant")
+	}
+
+	for _, variant := range []string{staticIntf.BLOG, staticIntf.NARRATIVES} {
+		if !strings.Contains(err.Error(), variant) {
+			t.Error("Expected error to list registered variant", variant, ", but got", err)
+		}
+	}
+}
+
+func TestTwoBlogSourcesRenderToTheirSubDirs(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "blogs")
+	defer os.RemoveAll(dir)
+	for _, blog := range []string{"blog", "news"} {
+		os.MkdirAll(filepath.Join(dir, blog), 0755)
+		ioutil.WriteFile(filepath.Join(dir, blog, "doc00000.md"), []byte(fmt.Sprintf(
+			"---\ntitle: Post of %s\ncreate_date: 2009-06-13\npath: /%s/post/\n---\nText\n", blog, blog)), 0644)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(fmt.Sprintf(`[{
+		"domain": "drewing.de",
+		"deploy": {"targetDir": "deploy"},
+		"src": [
+			{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog"},
+			{"dir": %q, "type": "blog", "subDir": "news", "headline": "News"}
+		]
+	}]`, filepath.Join(dir, "blog"), filepath.Join(dir, "news"))), 0644)
+
+	s := NewSiteCreator(staticPersistence.ReadConfig(dir, "config.json")[0])
+	s.options.jobs = 1
+	s.addSite()
+	s.addSources()
+	s.addContainers()
+	s.addContexts()
+
+	rendered := map[string][]string{}
+	for _, ctx := range s.contexts {
+		sc, ok := ctx.(*sourceContext)
+		if !ok || !strings.HasPrefix(sc.ID(), staticIntf.BLOG+":") {
+			continue
+		}
+		for _, fc := range sc.RenderPages() {
+			page := filepath.ToSlash(filepath.Join(fc.GetPath(), fc.GetFilename()))
+			rendered[sc.ID()] = append(rendered[sc.ID()], page)
+		}
+	}
+	if len(rendered) != 2 {
+		t.Fatal("Expected", 2, "blog contexts, but got", rendered)
+	}
+	for _, blog := range []string{"blog", "news"} {
+		pages := rendered[staticIntf.BLOG+":"+blog+":"+filepath.Join(dir, blog)]
+		if len(pages) == 0 {
+			t.Error("Expected pages of", blog, ", but got none")
+		}
+		for _, page := range pages {
+			if !strings.HasPrefix(page, "deploy/"+blog+"/") {
+				t.Error("Expected the pages of", blog, "below deploy/"+blog+"/, but got", page)
+			}
+		}
+	}
+}
+
+func TestSourcesOfOneDirRenderItsPagesOnce(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "marginals")
+	defer os.RemoveAll(dir)
+	marginals := filepath.Join(dir, "marginal")
+	os.MkdirAll(marginals, 0755)
+	ioutil.WriteFile(filepath.Join(marginals, "doc00000.md"), []byte(
+		"---\ntitle: Imprint\npath: /imprint/\n---\nText\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(fmt.Sprintf(`[{
+		"domain": "drewing.de",
+		"deploy": {"targetDir": "deploy"},
+		"src": [
+			{"dir": %q, "type": "marginal", "subDir": ""},
+			{"dir": %q, "type": "narrativeMarginal", "subDir": "devabo.de"}
+		]
+	}]`, marginals, marginals+"/")), 0644)
+
+	s := NewSiteCreator(staticPersistence.ReadConfig(dir, "config.json")[0])
+	s.options.jobs = 1
+	s.addSite()
+	s.addSources()
+	s.addContainers()
+	s.addContexts()
+
+	rendered := map[string]string{}
+	for _, ctx := range s.contexts {
+		for _, fc := range ctx.RenderPages() {
+			page := filepath.ToSlash(filepath.Join(fc.GetPath(), fc.GetFilename()))
+			if other, ok := rendered[page]; ok {
+				t.Error("Expected", page, "to be rendered once, but got it of", other, "and", identity(ctx))
+			}
+			rendered[page] = identity(ctx)
+		}
+	}
+	if _, ok := rendered["deploy/imprint/index.html"]; !ok {
+		t.Error("Expected the marginal page to be rendered, but got", rendered)
+	}
+}
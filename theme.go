@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ingmardrewing/fs"
+)
+
+const (
+	defaultThemesDir = "themes"
+
+	// optional file of a theme, naming the theme it
+	// inherits from and the default svg logo
+	themeFile = "theme.json"
+
+	// dir below the deploy dir the assets are copied to
+	themeTargetDir = "theme"
+)
+
+var cssUrlRegex = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+
+// Loads the theme of the given name from the themes
+// dir, along with the themes it inherits from
+func LoadTheme(themesDir, name string) (*theme, error) {
+	if themesDir == "" {
+		themesDir = defaultThemesDir
+	}
+	t := new(theme)
+	t.name = name
+	t.files = map[string]string{}
+	t.origins = map[string]int{}
+	if err := t.load(themesDir, name, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	if err := t.fingerprint(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// A theme is a dir with page templates below templates/,
+// css below css/, js below js/ and assets like fonts and
+// images in any other dir. The css and js become part of
+// the bundles of the site, the assets are copied into the
+// deploy dir below theme/, with their content hash added
+// to their names.
+type theme struct {
+	name    string
+	svgLogo string
+
+	// the files of the theme and of the themes it inherits
+	// from, by their path within the theme. Files replace
+	// inherited files of the same path.
+	files map[string]string
+
+	// the position of the theme each file is taken from,
+	// within the chain of inheritance, the base comes first
+	origins map[string]int
+	loaded  int
+
+	// the fingerprinted paths of the assets from the
+	// doc root, by their paths from the doc root
+	fingerprints map[string]string
+	assets       map[string][]byte
+}
+
+type themeMeta struct {
+	Inherits string `json:"inherits"`
+	SvgLogo  string `json:"svgLogo"`
+}
+
+func (t *theme) load(themesDir, name string, loading map[string]bool) error {
+	if loading[name] {
+		return fmt.Errorf("theme %s inherits from itself", name)
+	}
+	loading[name] = true
+
+	dir := filepath.Join(themesDir, name)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("theme %s not found in %s", name, themesDir)
+	}
+	meta := themeMeta{}
+	data, err := ioutil.ReadFile(filepath.Join(dir, themeFile))
+	if err == nil {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return fmt.Errorf("%s: %v", filepath.Join(dir, themeFile), err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if meta.Inherits != "" {
+		if err := t.load(themesDir, meta.Inherits, loading); err != nil {
+			return err
+		}
+	}
+	if meta.SvgLogo != "" {
+		t.svgLogo = meta.SvgLogo
+	}
+	origin := t.loaded
+	t.loaded++
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && p != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() || rel == themeFile {
+			return nil
+		}
+		t.files[rel] = p
+		t.origins[rel] = origin
+		return nil
+	})
+}
+
+// Reads the assets and adds the hash of their content to their names
+func (t *theme) fingerprint() error {
+	t.fingerprints = map[string]string{}
+	t.assets = map[string][]byte{}
+	for _, rel := range t.filesBelow("") {
+		if !isThemeAsset(rel) {
+			continue
+		}
+		data, err := ioutil.ReadFile(t.files[rel])
+		if err != nil {
+			return err
+		}
+		target := path.Join(themeTargetDir, rel)
+		hashed := path.Join(path.Dir(target), fingerprint(path.Base(target), string(data)))
+		t.fingerprints[target] = hashed
+		t.assets[hashed] = data
+	}
+	return nil
+}
+
+// Tells if the file of the theme is copied into the
+// deploy dir, instead of being used by the build
+func isThemeAsset(rel string) bool {
+	switch strings.SplitN(rel, "/", 2)[0] {
+	case "templates", "css", "js":
+		return false
+	}
+	return strings.Contains(rel, "/")
+}
+
+// Returns the sorted paths within the theme of
+// the files in the given dir of the theme, or of
+// all files if dir is empty
+func (t *theme) filesBelow(dir string) []string {
+	rels := []string{}
+	for rel := range t.files {
+		if dir == "" || path.Dir(rel) == dir {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Strings(rels)
+	return rels
+}
+
+// Returns the page templates of the theme
+func (t *theme) templates() []string {
+	files := []string{}
+	for _, rel := range t.filesBelow("templates") {
+		if path.Ext(rel) == ".html" {
+			files = append(files, t.files[rel])
+		}
+	}
+	return files
+}
+
+// Creates the file containers copying the fingerprinted assets into the deploy dir
+func (t *theme) fileContainers(targetDir string) []fs.FileContainer {
+	hashed := []string{}
+	for h := range t.assets {
+		hashed = append(hashed, h)
+	}
+	sort.Strings(hashed)
+
+	fcs := []fs.FileContainer{}
+	for _, h := range hashed {
+		fc := fs.NewFileContainer()
+		fc.SetData(t.assets[h])
+		fc.SetPath(filepath.Join(targetDir, filepath.FromSlash(path.Dir(h))))
+		fc.SetFilename(path.Base(h))
+		fcs = append(fcs, fc)
+	}
+	return fcs
+}
+
+// Creates the component joining the css and the js files of the theme
+func (t *theme) component() (*themeComponent, error) {
+	css, err := t.join("css", ".css")
+	if err != nil {
+		return nil, err
+	}
+	js, err := t.join("js", ".js")
+	if err != nil {
+		return nil, err
+	}
+	c := &themeComponent{name: t.name}
+	for _, part := range css {
+		c.css += t.rewriteCssUrls(part.rel, part.content)
+	}
+	for _, part := range js {
+		c.js += part.content
+	}
+	return c, nil
+}
+
+// A file of the theme
+type themePart struct {
+	rel     string
+	content string
+}
+
+// Reads the files of the given dir and type, those of
+// inherited themes first, so the css of a theme can
+// override the css it inherits
+func (t *theme) join(dir, ext string) ([]themePart, error) {
+	rels := t.filesBelow(dir)
+	sort.SliceStable(rels, func(i, j int) bool {
+		return t.origins[rels[i]] < t.origins[rels[j]]
+	})
+	parts := []themePart{}
+	for _, rel := range rels {
+		if path.Ext(rel) != ext {
+			continue
+		}
+		data, err := ioutil.ReadFile(t.files[rel])
+		if err != nil {
+			return nil, err
+		}
+		content := string(data)
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		parts = append(parts, themePart{rel, content})
+	}
+	return parts, nil
+}
+
+// Points the urls of the css file of the theme, which are
+// relative to the file or start with /theme/, to the
+// fingerprinted assets, as the css is moved into the bundle
+func (t *theme) rewriteCssUrls(rel, css string) string {
+	return cssUrlRegex.ReplaceAllStringFunc(css, func(u string) string {
+		m := cssUrlRegex.FindStringSubmatch(u)
+		ref := m[2]
+		suffix := ""
+		if i := strings.IndexAny(ref, "?#"); i >= 0 {
+			ref, suffix = ref[:i], ref[i:]
+		}
+		var target string
+		switch {
+		case strings.Contains(ref, ":") || strings.HasPrefix(ref, "//"):
+			return u
+		case strings.HasPrefix(ref, "/"):
+			target = strings.TrimPrefix(path.Clean(ref), "/")
+		default:
+			target = path.Join(themeTargetDir, path.Dir(rel), ref)
+		}
+		hashed, ok := t.fingerprints[target]
+		if !ok {
+			return u
+		}
+		return "url(" + m[1] + "/" + hashed + suffix + m[3] + ")"
+	})
+}
+
+// The css and the js of a theme, added to the bundles
+// after the components of the contexts, so the theme
+// can override their styles
+type themeComponent struct {
+	name string
+	css  string
+	js   string
+}
+
+func (c *themeComponent) ID() string {
+	return "theme:" + c.name
+}
+
+func (c *themeComponent) GetCss() string {
+	return c.css
+}
+
+func (c *themeComponent) GetJs() string {
+	return c.js
+}
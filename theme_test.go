@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Creates a themes dir with the theme base and
+// the theme child inheriting from it
+func givenThemes() string {
+	dir, _ := ioutil.TempDir("", "themes")
+	files := map[string]string{
+		"base/theme.json":          `{"svgLogo": "/theme/images/logo.svg"}`,
+		"base/css/main.css":        "body { background: url(../images/bg.png); }\n",
+		"base/images/bg.png":       "base png",
+		"base/images/logo.svg":     "<svg></svg>",
+		"base/fonts/serif.woff2":   "font",
+		"base/templates/post.html": `{{template "head" .}}<article>{{.Page.Title}}</article>{{template "footer" .}}`,
+		"child/theme.json":         `{"inherits": "base"}`,
+		"child/css/extra.css":      "@font-face { src: url('/theme/fonts/serif.woff2?v=1'); }",
+		"child/js/menu.js":         "var menu = true;",
+		"child/images/bg.png":      "child png",
+		"child/.hidden/x.png":      "hidden"}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(file), 0755)
+		ioutil.WriteFile(file, []byte(content), 0644)
+	}
+	return dir
+}
+
+func TestThemeInheritsFiles(t *testing.T) {
+	dir := givenThemes()
+	defer os.RemoveAll(dir)
+
+	th, err := LoadTheme(dir, "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.svgLogo != "/theme/images/logo.svg" {
+		t.Error("Expected the svg logo of the base theme, but got", th.svgLogo)
+	}
+	if th.files["images/bg.png"] != filepath.Join(dir, "child", "images", "bg.png") {
+		t.Error("Expected bg.png of the child theme, but got", th.files["images/bg.png"])
+	}
+	if _, ok := th.files[".hidden/x.png"]; ok {
+		t.Error("Expected hidden files to be left out")
+	}
+
+	bg := th.fingerprints["theme/images/bg.png"]
+	expected := "theme/images/bg." + contentHash("child png")[:bundleHashLength] + ".png"
+	if bg != expected {
+		t.Error("Expected", expected, ", but got", bg)
+	}
+	if len(th.fingerprints) != 3 {
+		t.Error("Expected", 3, "assets, but got", th.fingerprints)
+	}
+
+	cmp, err := th.component()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "body { background: url(/" + bg + "); }\n" +
+		"@font-face { src: url('/" + th.fingerprints["theme/fonts/serif.woff2"] + "?v=1'); }\n"
+	if cmp.GetCss() != expected {
+		t.Error("Expected", expected, ", but got", cmp.GetCss())
+	}
+	if cmp.GetJs() != "var menu = true;\n" {
+		t.Error("Expected the js of the child theme, but got", cmp.GetJs())
+	}
+}
+
+func TestThemeErrors(t *testing.T) {
+	dir := givenThemes()
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "base", "theme.json"), []byte(`{"inherits": "child"}`), 0644)
+
+	if _, err := LoadTheme(dir, "child"); err == nil || !strings.Contains(err.Error(), "inherits from itself") {
+		t.Error("Expected an error for the cycle, but got", err)
+	}
+	if _, err := LoadTheme(dir, "missing"); err == nil {
+		t.Error("Expected an error for a missing theme")
+	}
+}
+
+func TestSiteUsesTheme(t *testing.T) {
+	themes := givenThemes()
+	defer os.RemoveAll(themes)
+	s := givenRenderedSite(t, func(posts string) string {
+		return fmt.Sprintf(`[{
+			"domain": "drewing.de",
+			"deploy": {"targetDir": "deploy", "cssFileName": "styles.css"},
+			"src": [{"dir": %q, "type": "blog", "subDir": "blog", "headline": "Blog"}],
+			"theme": "child",
+			"themesDir": %q
+		}]`, posts, themes)
+	})
+
+	files := renderedFiles(s, nil)
+	bg := s.theme.fingerprints["theme/images/bg.png"]
+	if files["deploy/"+bg] != "child png" {
+		t.Error("Expected the fingerprinted asset to be copied, but got", files["deploy/"+bg])
+	}
+	post := files["deploy/blog/post-0/index.html"]
+	if !strings.Contains(post, "<article>Post &amp; 0</article>") {
+		t.Error("Expected the post rendered by the template of the theme, but got", post)
+	}
+	css := ""
+	for name, content := range files {
+		if strings.HasPrefix(name, "deploy/styles.") {
+			css = content
+		}
+	}
+	if !strings.Contains(css, "url(/"+bg+")") {
+		t.Error("Expected the css of the theme within the bundle, but got", css)
+	}
+	js := ""
+	for name := range files {
+		if strings.HasPrefix(name, "deploy/logic.") {
+			js = strings.TrimPrefix(name, "deploy")
+		}
+	}
+	if js == "" || !strings.Contains(post, `<script src="`+js+`" defer></script>`) {
+		t.Error("Expected the post to load the js bundle", js, ", but got", post)
+	}
+	if s.svgLogo() != "/theme/images/logo.svg" {
+		t.Error("Expected the svg logo of the theme, but got", s.svgLogo())
+	}
+}
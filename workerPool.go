@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Runs the given tasks on at most the given number of
+// goroutines. The returned slice holds the error of each
+// task at the task's index, panics are returned as errors.
+// With one job or less the tasks run one after another
+// on the calling goroutine.
+func runParallel(jobs int, tasks []func() error) []error {
+	errs := make([]error, len(tasks))
+	if jobs <= 1 {
+		for i, task := range tasks {
+			errs[i] = runTask(task)
+		}
+		return errs
+	}
+
+	indices := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < jobs && w < len(tasks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				errs[i] = runTask(tasks[i])
+			}
+		}()
+	}
+	for i := range tasks {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return errs
+}
+
+func runTask(task func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return task()
+}
+
+// Collects the errors which occurred while
+// building the site of the given domain
+type siteErrors struct {
+	domain string
+	errs   []error
+}
+
+// Adds the given errors, ignoring nil values
+func (s *siteErrors) add(errs ...error) {
+	for _, err := range errs {
+		if err != nil {
+			s.errs = append(s.errs, err)
+		}
+	}
+}
+
+// Returns nil if no errors have been added,
+// so the result can be returned as error
+func (s *siteErrors) orNil() error {
+	if len(s.errs) == 0 {
+		return nil
+	}
+	return s
+}
+
+func (s *siteErrors) Error() string {
+	msgs := []string{}
+	for _, err := range s.errs {
+		msgs = append(msgs, "  "+err.Error())
+	}
+	return fmt.Sprintf("%s: %d error(s)\n%s",
+		s.domain, len(s.errs), strings.Join(msgs, "\n"))
+}
+
+// Combines the errors of several sites into one
+type buildErrors []error
+
+func (b buildErrors) Error() string {
+	msgs := []string{}
+	for _, err := range b {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunParallelKeepsTaskOrder(t *testing.T) {
+	results := make([]int, 100)
+	tasks := []func() error{}
+	for i := range results {
+		i := i
+		tasks = append(tasks, func() error {
+			results[i] = i * i
+			if i%10 == 0 {
+				return errors.New("failed")
+			}
+			return nil
+		})
+	}
+
+	errs := runParallel(8, tasks)
+
+	for i, r := range results {
+		if r != i*i {
+			t.Error("Expected", i*i, "at index", i, ", but got", r)
+		}
+		if (errs[i] != nil) != (i%10 == 0) {
+			t.Error("Unexpected error at index", i, ":", errs[i])
+		}
+	}
+}
+
+func TestRunParallelLimitsJobs(t *testing.T) {
+	var running, max int32
+	tasks := []func() error{}
+	for i := 0; i < 50; i++ {
+		tasks = append(tasks, func() error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+	}
+
+	runParallel(3, tasks)
+
+	if max > 3 {
+		t.Error("Expected at most 3 concurrent tasks, but got", max)
+	}
+}
+
+func TestRunParallelRecoversPanics(t *testing.T) {
+	errs := runParallel(2, []func() error{
+		func() error { panic("boom") },
+		func() error { return nil }})
+
+	if errs[0] == nil || errs[0].Error() != "panic: boom" {
+		t.Error("Expected panic to be returned as error, but got", errs[0])
+	}
+	if errs[1] != nil {
+		t.Error("Expected no error, but got", errs[1])
+	}
+}